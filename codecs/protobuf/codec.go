@@ -0,0 +1,19 @@
+package subee_protobuf
+
+import (
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec returns a subee.Codec that decodes Protocol Buffers payloads into T.
+func Codec[T proto.Message]() subee.Codec[T] {
+	return subee.CodecFunc[T](func(data []byte) (T, error) {
+		var zero T
+		obj := zero.ProtoReflect().New().Interface().(T)
+		if err := proto.Unmarshal(data, obj); err != nil {
+			return zero, errors.WithStack(err)
+		}
+		return obj, nil
+	})
+}
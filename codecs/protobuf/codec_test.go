@@ -0,0 +1,27 @@
+package subee_protobuf
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCodec(t *testing.T) {
+	data, err := proto.Marshal(wrapperspb.String("foo"))
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	got, err := Codec[*wrapperspb.StringValue]().Decode(data)
+	if err != nil {
+		t.Fatalf("Decode() returned %v, want nil", err)
+	}
+	if got, want := got.GetValue(), "foo"; got != want {
+		t.Errorf("Decode() returned %q, want %q", got, want)
+	}
+
+	if _, err := Codec[*wrapperspb.StringValue]().Decode([]byte{0xff}); err == nil {
+		t.Error("Decode() returned nil, want an error")
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/wantedly/subee/codecs/protobuf
+
+go 1.23
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+	google.golang.org/protobuf v1.36.10
+)
+
+replace github.com/wantedly/subee => ../..
@@ -22,6 +22,17 @@ type Config struct {
 
 	FlushInterval time.Duration
 
+	MaxConcurrency int
+
+	ShutdownTimeout time.Duration
+
+	ConsumeTimeout time.Duration
+
+	Label string
+
+	OrderingKeyFunc OrderingKeyFunc
+	OrderingPolicy  OrderingPolicy
+
 	Logger Logger
 
 	StatsHandler StatsHandler
@@ -31,6 +42,9 @@ type Config struct {
 
 	Consumer             Consumer
 	ConsumerInterceptors []ConsumerInterceptor
+
+	// sem is the consumption slots shared with other engines.
+	sem chan struct{}
 }
 
 func (c *Config) apply(opts []Option) {
@@ -7,6 +7,7 @@ import (
 
 type (
 	loggerContextKey     struct{}
+	labelContextKey      struct{}
 	enqueuedAtContextKey struct{}
 )
 
@@ -19,6 +20,16 @@ func setLogger(ctx context.Context, l Logger) context.Context {
 	return context.WithValue(ctx, loggerContextKey{}, l)
 }
 
+// GetLabel return the label of Engine set in the context.
+func GetLabel(ctx context.Context) string {
+	label, _ := ctx.Value(labelContextKey{}).(string)
+	return label
+}
+
+func setLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, labelContextKey{}, label)
+}
+
 func getEnqueuedAt(ctx context.Context) time.Time {
 	return ctx.Value(enqueuedAtContextKey{}).(time.Time)
 }
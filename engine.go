@@ -2,6 +2,8 @@ package subee
 
 import (
 	"context"
+	"io"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -10,8 +12,25 @@ import (
 type Engine struct {
 	*Config
 	subscriber Subscriber
+
+	mu      sync.Mutex
+	process process
+	stopped bool
+}
+
+// ShutdownReport represents how received messages were settled on shutdown.
+type ShutdownReport struct {
+	// Acked is the number of messages acked while draining.
+	Acked int
+	// Nacked is the number of messages nacked while draining.
+	Nacked int
+	// Abandoned is the number of messages nacked because their consumption did not finish before the deadline.
+	Abandoned int
 }
 
+// ErrAbandoned is the error that messages were nacked on shutdown because their consumption did not finish before the deadline.
+var ErrAbandoned = errors.New("messages were abandoned on shutdown")
+
 // New creates a Engine intstance with Consumer.
 func New(subscriber Subscriber, consumer Consumer, opts ...Option) *Engine {
 	return newEngine(subscriber, nil, consumer, opts...)
@@ -28,6 +47,10 @@ func newEngine(subscriber Subscriber, bConsumer BatchConsumer, consumer Consumer
 	cfg.Consumer = consumer
 	cfg.apply(opts)
 
+	if cfg.Label != "" {
+		cfg.Logger = &labeledLogger{Logger: cfg.Logger, prefix: "[" + cfg.Label + "] "}
+	}
+
 	e := &Engine{
 		Config:     cfg,
 		subscriber: subscriber,
@@ -37,11 +60,65 @@ func newEngine(subscriber Subscriber, bConsumer BatchConsumer, consumer Consumer
 }
 
 // Start starts Subscriber and Consumer process.
+// When Start returns, the Subscriber is closed if it implements io.Closer.
+// Start returns immediately if Stop has already been called.
 func (e *Engine) Start(ctx context.Context) error {
 	e.Logger.Print("Start Pub/Sub worker")
 	defer e.Logger.Print("Finish Pub/Sub worker")
 
 	ctx = setLogger(ctx, e.Logger)
+	ctx = setLabel(ctx, e.Label)
+
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return errors.WithStack(e.closeSubscriber())
+	}
+	p := newProcess(e)
+	e.process = p
+	e.mu.Unlock()
+
+	err := p.Start(ctx)
+
+	if cerr := e.closeSubscriber(); err == nil {
+		err = cerr
+	}
+
+	return errors.WithStack(err)
+}
+
+func (e *Engine) closeSubscriber() error {
+	c, ok := e.subscriber.(io.Closer)
+	if !ok {
+		return nil
+	}
+
+	if err := c.Close(); err != nil {
+		e.Logger.Printf("Failed to close subscriber: %v", err)
+		return errors.Wrap(err, "failed to close subscriber")
+	}
+
+	return nil
+}
+
+// Stop stops receiving messages, flushes buffered messages and waits for their consumption.
+// Messages whose consumption has not finished until ctx is done or ShutdownTimeout elapses are nacked,
+// and then ErrAbandoned is returned with the report.
+// The stop request is kept on the Engine, so Stop may be called before Start, and Start called afterwards returns immediately.
+func (e *Engine) Stop(ctx context.Context) (*ShutdownReport, error) {
+	e.mu.Lock()
+	e.stopped = true
+	p := e.process
+	e.mu.Unlock()
+
+	if p == nil {
+		return new(ShutdownReport), nil
+	}
+
+	report := p.Stop(ctx)
+	if report.Abandoned > 0 {
+		return report, errors.WithStack(ErrAbandoned)
+	}
 
-	return errors.WithStack(newProcess(e).Start(ctx))
+	return report, nil
 }
@@ -7,6 +7,7 @@ import (
 	"log"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -238,3 +239,513 @@ func TestEngineWithBatchConsumer(t *testing.T) {
 		})
 	}
 }
+
+func TestEngineWithMaxConcurrency(t *testing.T) {
+	const (
+		maxConcurrency = 2
+		msgCount       = 6
+	)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var (
+		inFlight, maxInFlight, consumed int32
+		msgs                            []*subee_testing.FakeMessage
+	)
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if atomic.AddInt32(&consumed, 1) == msgCount {
+			defer cancel()
+		}
+		return nil
+	})
+
+	engine := subee.New(
+		subscriber,
+		consumer,
+		subee.WithMaxConcurrency(maxConcurrency),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(3 * time.Millisecond)
+		for i := 0; i < msgCount; i++ {
+			m := subee_testing.NewFakeMessage([]byte("foo"), false, false)
+			msgs = append(msgs, m)
+			subscriber.AddMessage(m)
+		}
+	}()
+
+	err := engine.Start(ctx)
+	if err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+	wg.Wait()
+
+	if got, want := atomic.LoadInt32(&maxInFlight), int32(maxConcurrency); got > want {
+		t.Errorf("%d messages consumed concurrently, want at most %d", got, want)
+	}
+	for i, m := range msgs {
+		if !m.Acked() {
+			t.Errorf("Messages[%d].Acked() is false, want true", i)
+		}
+	}
+}
+
+func TestEngine_Stop(t *testing.T) {
+	type TestCase struct {
+		test    string
+		timeout time.Duration
+		release time.Duration
+		want    subee.ShutdownReport
+		wantErr error
+		acked   bool
+		nacked  bool
+	}
+
+	cases := []TestCase{
+		{
+			test:    "acked when consumed before deadline",
+			timeout: 50 * time.Millisecond,
+			release: 3 * time.Millisecond,
+			want:    subee.ShutdownReport{Acked: 1},
+			acked:   true,
+		},
+		{
+			test:    "nacked when not consumed before deadline",
+			timeout: 3 * time.Millisecond,
+			release: 20 * time.Millisecond,
+			want:    subee.ShutdownReport{Abandoned: 1},
+			wantErr: subee.ErrAbandoned,
+			nacked:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.test, func(t *testing.T) {
+			started, finished := make(chan struct{}), make(chan struct{})
+			subscriber := subee_testing.NewFakeSubscriber()
+			consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+				close(started)
+				defer close(finished)
+				time.Sleep(tc.release)
+				return nil
+			})
+
+			engine := subee.New(
+				subscriber,
+				consumer,
+				subee.WithShutdownTimeout(tc.timeout),
+				subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+			)
+
+			errCh := make(chan error, 1)
+			go func() { errCh <- engine.Start(context.Background()) }()
+
+			msg := subee_testing.NewFakeMessage([]byte("foo"), false, false)
+			subscriber.AddMessage(msg)
+			<-started
+
+			report, err := engine.Stop(context.Background())
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Stop returned an error %v, want %v", err, tc.wantErr)
+			}
+			if err := <-errCh; err != nil {
+				t.Errorf("Start returned an error: %v", err)
+			}
+			<-finished
+			time.Sleep(3 * time.Millisecond)
+
+			if got, want := *report, tc.want; got != want {
+				t.Errorf("Stop returned %+v, want %+v", got, want)
+			}
+			if got, want := msg.Acked(), tc.acked; got != want {
+				t.Errorf("Message.Acked() is %t, want %t", got, want)
+			}
+			if got, want := msg.Nacked(), tc.nacked; got != want {
+				t.Errorf("Message.Nacked() is %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestEngine_StopWithBatchConsumer(t *testing.T) {
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+		return nil
+	})
+
+	engine := subee.NewBatch(
+		subscriber,
+		consumer,
+		subee.WithChunkSize(4),
+		subee.WithFlushInterval(time.Hour),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(context.Background()) }()
+
+	msgs := []*subee_testing.FakeMessage{
+		subee_testing.NewFakeMessage([]byte("foo"), false, false),
+		subee_testing.NewFakeMessage([]byte("bar"), false, false),
+	}
+	for _, m := range msgs {
+		subscriber.AddMessage(m)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	report, err := engine.Stop(ctx)
+	if err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := *report, (subee.ShutdownReport{Acked: 2}); got != want {
+		t.Errorf("Stop returned %+v, want %+v", got, want)
+	}
+	for i, m := range msgs {
+		if !m.Acked() {
+			t.Errorf("Messages[%d].Acked() is false, want true", i)
+		}
+	}
+}
+
+func TestEngine_StopBeforeStart(t *testing.T) {
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		return nil
+	})
+
+	engine := subee.New(
+		subee_testing.NewFakeSubscriber(),
+		consumer,
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	report, err := engine.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+	if got, want := *report, (subee.ShutdownReport{}); got != want {
+		t.Errorf("Stop returned %+v, want %+v", got, want)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(context.Background()) }()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("Start returned an error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start should return immediately after Stop")
+	}
+}
+
+type endStatsHandler struct {
+	subee.NopStatsHandler
+	mu   sync.Mutex
+	ends []*subee.End
+}
+
+func (h *endStatsHandler) HandleProcess(ctx context.Context, s subee.Stats) {
+	if s, ok := s.(*subee.End); ok {
+		h.mu.Lock()
+		h.ends = append(h.ends, s)
+		h.mu.Unlock()
+	}
+}
+
+func TestEngineWithBatchConsumer_BatchError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+		defer cancel()
+		return subee.BatchError{1: errors.New("error")}
+	})
+	statsHandler := new(endStatsHandler)
+
+	engine := subee.NewBatch(
+		subscriber,
+		consumer,
+		subee.WithChunkSize(3),
+		subee.WithStatsHandler(statsHandler),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	msgs := []*subee_testing.FakeMessage{
+		subee_testing.NewFakeMessage([]byte("foo"), false, false),
+		subee_testing.NewFakeMessage([]byte("error!!"), false, false),
+		subee_testing.NewFakeMessage([]byte("bar"), false, false),
+	}
+	go func() {
+		for _, m := range msgs {
+			subscriber.AddMessage(m)
+		}
+	}()
+
+	err := engine.Start(ctx)
+	if err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	for i, want := range []bool{true, false, true} {
+		if got := msgs[i].Acked(); got != want {
+			t.Errorf("Messages[%d].Acked() is %t, want %t", i, got, want)
+		}
+		if got := msgs[i].Nacked(); got != !want {
+			t.Errorf("Messages[%d].Nacked() is %t, want %t", i, got, !want)
+		}
+	}
+
+	if got, want := len(statsHandler.ends), 1; got != want {
+		t.Fatalf("End stats reported %d times, want %d", got, want)
+	}
+	if got, want := statsHandler.ends[0].AckCount, 2; got != want {
+		t.Errorf("End.AckCount is %d, want %d", got, want)
+	}
+	if got, want := statsHandler.ends[0].NackCount, 1; got != want {
+		t.Errorf("End.NackCount is %d, want %d", got, want)
+	}
+}
+
+type engineTestContextKey struct{}
+
+func TestEngine_ConsumingContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), engineTestContextKey{}, "foo"))
+
+	errCh := make(chan error, 1)
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		defer close(errCh)
+
+		if got, want := ctx.Value(engineTestContextKey{}), "foo"; got != want {
+			t.Errorf("ctx.Value() returned %v, want %v", got, want)
+		}
+		if subee.GetLogger(ctx) == nil {
+			t.Error("GetLogger() returned nil")
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("ctx.Deadline() is not set")
+		}
+
+		cancel()
+		time.Sleep(3 * time.Millisecond)
+		if err := ctx.Err(); err != nil {
+			t.Errorf("consuming context is canceled with Start context: %v", err)
+		}
+		return nil
+	})
+
+	engine := subee.New(
+		subscriber,
+		consumer,
+		subee.WithConsumeTimeout(time.Minute),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	go subscriber.AddMessage(subee_testing.NewFakeMessage([]byte("foo"), false, false))
+
+	if err := engine.Start(ctx); err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+	<-errCh
+}
+
+type dequeueStatsHandler struct {
+	subee.NopStatsHandler
+	mu       sync.Mutex
+	dequeues []*subee.Dequeue
+}
+
+func (h *dequeueStatsHandler) HandleProcess(ctx context.Context, s subee.Stats) {
+	if s, ok := s.(*subee.Dequeue); ok {
+		h.mu.Lock()
+		h.dequeues = append(h.dequeues, s)
+		h.mu.Unlock()
+	}
+}
+
+func TestEngineWithBatchConsumer_PublishTime(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+		defer cancel()
+		return nil
+	})
+	statsHandler := new(dequeueStatsHandler)
+
+	engine := subee.NewBatch(
+		subscriber,
+		consumer,
+		subee.WithChunkSize(3),
+		subee.WithStatsHandler(statsHandler),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	published := time.Now().Add(-time.Minute)
+	go func() {
+		subscriber.AddMessage(subee_testing.NewFakeExtendedMessage([]byte("foo"), "1", published.Add(time.Second), 1, ""))
+		subscriber.AddMessage(subee_testing.NewFakeMessage([]byte("bar"), false, false))
+		subscriber.AddMessage(subee_testing.NewFakeExtendedMessage([]byte("baz"), "2", published, 1, ""))
+	}()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := len(statsHandler.dequeues), 1; got != want {
+		t.Fatalf("Dequeue stats reported %d times, want %d", got, want)
+	}
+	if got, want := statsHandler.dequeues[0].PublishTime, published; !got.Equal(want) {
+		t.Errorf("Dequeue.PublishTime is %v, want %v", got, want)
+	}
+}
+
+type ackStatsHandler struct {
+	subee.NopStatsHandler
+	mu        sync.Mutex
+	ackErrors []*subee.AckError
+	ends      []*subee.End
+}
+
+func (h *ackStatsHandler) HandleProcess(ctx context.Context, s subee.Stats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch s := s.(type) {
+	case *subee.AckError:
+		h.ackErrors = append(h.ackErrors, s)
+	case *subee.End:
+		h.ends = append(h.ends, s)
+	}
+}
+
+func TestEngine_AckResult(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var consumed int32
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		if atomic.AddInt32(&consumed, 1) == 2 {
+			defer cancel()
+		}
+		if string(msg.Data()) == "nack" {
+			return errors.New("error")
+		}
+		return nil
+	})
+	statsHandler := new(ackStatsHandler)
+
+	engine := subee.New(
+		subscriber,
+		consumer,
+		subee.WithStatsHandler(statsHandler),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	ackErr := errors.New("ack failed")
+	acked := subee_testing.NewFakeResultMessage([]byte("ack"), ackErr, nil)
+	nacked := subee_testing.NewFakeResultMessage([]byte("nack"), nil, nil)
+	go func() {
+		subscriber.AddMessage(acked)
+		subscriber.AddMessage(nacked)
+	}()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if !acked.Acked() {
+		t.Error("The message should be acked")
+	}
+	if !nacked.Nacked() {
+		t.Error("The message should be nacked")
+	}
+
+	if got, want := len(statsHandler.ackErrors), 1; got != want {
+		t.Fatalf("AckError stats reported %d times, want %d", got, want)
+	}
+	if got := statsHandler.ackErrors[0]; !got.Ack || got.Error != ackErr {
+		t.Errorf("AckError is %+v, want a failed ack with %v", got, ackErr)
+	}
+
+	var ackErrorCount int
+	for _, end := range statsHandler.ends {
+		ackErrorCount += end.AckErrorCount
+	}
+	if got, want := ackErrorCount, 1; got != want {
+		t.Errorf("End.AckErrorCount is %d in total, want %d", got, want)
+	}
+}
+
+type closableSubscriber struct {
+	*subee_testing.FakeSubscriber
+	closed int32
+	err    error
+}
+
+func (s *closableSubscriber) Close() error {
+	atomic.AddInt32(&s.closed, 1)
+	return s.err
+}
+
+func TestEngine_CloseSubscriber(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "succeeded"},
+		{name: "failed", err: errors.New("close failed"), wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			subscriber := &closableSubscriber{FakeSubscriber: subee_testing.NewFakeSubscriber(), err: tc.err}
+			consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+				defer cancel()
+				if got := atomic.LoadInt32(&subscriber.closed); got != 0 {
+					t.Error("Subscriber should not be closed while consuming")
+				}
+				return nil
+			})
+
+			engine := subee.New(
+				subscriber,
+				consumer,
+				subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+			)
+
+			go subscriber.AddMessage(subee_testing.NewFakeMessage([]byte("foo"), false, false))
+
+			if err := engine.Start(ctx); (err != nil) != tc.wantErr {
+				t.Errorf("Start returned %v, want error %t", err, tc.wantErr)
+			}
+			if got, want := atomic.LoadInt32(&subscriber.closed), int32(1); got != want {
+				t.Errorf("Subscriber closed %d times, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package subee
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BatchError represents errors of the individual messages passed to BatchConsumer.BatchConsume.
+// Its key is the index of the failed message.
+// When BatchConsumer returns BatchError, only the failed messages are nacked and the others are acked.
+type BatchError map[int]error
+
+func (e BatchError) Error() string {
+	idxs := make([]int, 0, len(e))
+	for i := range e {
+		idxs = append(idxs, i)
+	}
+	sort.Ints(idxs)
+
+	msgs := make([]string, 0, len(e))
+	for _, i := range idxs {
+		msgs = append(msgs, fmt.Sprintf("[%d] %v", i, e[i]))
+	}
+
+	return fmt.Sprintf("failed to consume %d messages: %s", len(e), strings.Join(msgs, ", "))
+}
+
+// Skip is the error to ack the message without regarding its consumption as failed.
+var Skip = errors.New("skip the message")
+
+// Permanent wraps err to ack the message and report it as dropped since redelivering it never succeeds.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retryable wraps err to nack the message so that it is redelivered.
+// Errors not wrapped by Permanent are regarded as retryable by default.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// IsPermanent reports whether err is wrapped by Permanent.
+func IsPermanent(err error) bool { return classifyError(err) == permanentErrorKind }
+
+// IsRetryable reports whether the message failed with err should be redelivered.
+func IsRetryable(err error) bool { return classifyError(err) == retryableErrorKind }
+
+// IsSkip reports whether err is Skip.
+func IsSkip(err error) bool { return classifyError(err) == skipErrorKind }
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string        { return e.err.Error() }
+func (e *permanentError) Cause() error         { return e.err }
+func (e *permanentError) Unwrap() error        { return e.err }
+func (e *permanentError) errorKind() errorKind { return permanentErrorKind }
+
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string        { return e.err.Error() }
+func (e *retryableError) Cause() error         { return e.err }
+func (e *retryableError) Unwrap() error        { return e.err }
+func (e *retryableError) errorKind() errorKind { return retryableErrorKind }
+
+type errorKind int
+
+const (
+	noErrorKind errorKind = iota
+	retryableErrorKind
+	permanentErrorKind
+	skipErrorKind
+)
+
+// classifiedError is implemented by the errors wrapped by Permanent and Retryable.
+type classifiedError interface {
+	error
+	errorKind() errorKind
+}
+
+// classifyError returns the kind of the outermost classified error in the chain of err.
+func classifyError(err error) errorKind {
+	if err == nil {
+		return noErrorKind
+	}
+
+	var classified classifiedError
+	if errors.As(err, &classified) {
+		return classified.errorKind()
+	}
+	if errors.Is(err, Skip) {
+		return skipErrorKind
+	}
+
+	return retryableErrorKind
+}
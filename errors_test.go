@@ -0,0 +1,55 @@
+package subee
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type unwrapError struct{ err error }
+
+func (e *unwrapError) Error() string { return fmt.Sprintf("unwrap: %v", e.err) }
+func (e *unwrapError) Unwrap() error { return e.err }
+
+func TestClassifyError(t *testing.T) {
+	err := errors.New("error")
+
+	tests := []struct {
+		err  error
+		want errorKind
+	}{
+		{err: nil, want: noErrorKind},
+		{err: err, want: retryableErrorKind},
+		{err: Retryable(err), want: retryableErrorKind},
+		{err: Permanent(err), want: permanentErrorKind},
+		{err: Skip, want: skipErrorKind},
+		{err: errors.WithStack(Permanent(err)), want: permanentErrorKind},
+		{err: errors.Wrap(Skip, "wrapped"), want: skipErrorKind},
+		{err: &unwrapError{err: Permanent(err)}, want: permanentErrorKind},
+		{err: fmt.Errorf("wrapped: %w", Skip), want: skipErrorKind},
+		{err: Retryable(Permanent(err)), want: retryableErrorKind},
+		{err: Permanent(Retryable(err)), want: permanentErrorKind},
+	}
+
+	for _, test := range tests {
+		if got, want := classifyError(test.err), test.want; got != want {
+			t.Errorf("classifyError(%v) returned %v, want %v", test.err, got, want)
+		}
+	}
+}
+
+func TestPermanentAndRetryable_Unwrap(t *testing.T) {
+	errFoo := errors.New("foo")
+
+	for _, err := range []error{Permanent(errFoo), Retryable(errFoo)} {
+		if !errors.Is(err, errFoo) {
+			t.Errorf("errors.Is(%#v, errFoo) returned false, want true", err)
+		}
+
+		var target *unwrapError
+		if !errors.As(Permanent(&unwrapError{err: err}), &target) {
+			t.Errorf("errors.As(%#v) returned false, want true", err)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 module github.com/wantedly/subee
 
-go 1.11
+go 1.21
 
-require github.com/pkg/errors v0.8.1
+require github.com/pkg/errors v0.9.1
@@ -0,0 +1,142 @@
+package subee
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Group runs multiple engines under one lifecycle.
+// Engines in the group share the options and the concurrency budget given to NewGroup,
+// and all of them are stopped when any of them fails.
+type Group struct {
+	opts []Option
+	sem  chan struct{}
+
+	mu      sync.Mutex
+	engines []*Engine
+}
+
+// NewGroup creates a Group instance with the options shared with all engines.
+// When WithMaxConcurrency is given, it limits the number of messages consumed concurrently across the group.
+// WithMaxConcurrency given to an engine additionally limits the messages consumed concurrently by the engine.
+func NewGroup(opts ...Option) *Group {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	g := &Group{
+		opts: opts,
+	}
+	if cfg.MaxConcurrency > 0 {
+		g.sem = make(chan struct{}, cfg.MaxConcurrency)
+	}
+
+	return g
+}
+
+// Add creates a Engine with Consumer and adds it to the group.
+// The label identifies the engine in logs and stats, and opts override the shared options.
+func (g *Group) Add(label string, subscriber Subscriber, consumer Consumer, opts ...Option) *Engine {
+	return g.add(newEngine(subscriber, nil, consumer, g.engineOptions(label, opts)...))
+}
+
+// AddBatch creates a Engine with BatchConsumer and adds it to the group.
+// The label identifies the engine in logs and stats, and opts override the shared options.
+func (g *Group) AddBatch(label string, subscriber Subscriber, consumer BatchConsumer, opts ...Option) *Engine {
+	return g.add(newEngine(subscriber, consumer, nil, g.engineOptions(label, opts)...))
+}
+
+func (g *Group) engineOptions(label string, opts []Option) []Option {
+	engineOpts := make([]Option, 0, len(g.opts)+len(opts)+2)
+	engineOpts = append(engineOpts, g.opts...)
+	engineOpts = append(engineOpts, opts...)
+	engineOpts = append(engineOpts, WithLabel(label))
+	if g.sem != nil {
+		engineOpts = append(engineOpts, withSemaphore(g.sem))
+	}
+	return engineOpts
+}
+
+func (g *Group) add(e *Engine) *Engine {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.engines = append(g.engines, e)
+
+	return e
+}
+
+func (g *Group) list() []*Engine {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]*Engine(nil), g.engines...)
+}
+
+// Start starts all engines in the group and blocks until all of them finish.
+// When any engine fails, the others are stopped and the first error is returned.
+func (g *Group) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for _, e := range g.list() {
+		e := e
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.Start(ctx); err != nil {
+				errOnce.Do(func() {
+					firstErr = errors.Wrapf(err, "engine %q failed", e.Label)
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// Stop stops all engines in the group concurrently and returns the sum of their shutdown reports.
+// The sum is returned even if some engines return errors, e.g. ErrAbandoned.
+func (g *Group) Stop(ctx context.Context) (*ShutdownReport, error) {
+	engines := g.list()
+
+	var (
+		wg      sync.WaitGroup
+		reports = make([]*ShutdownReport, len(engines))
+		errs    = make([]error, len(engines))
+	)
+
+	for i, e := range engines {
+		i, e := i, e
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[i], errs[i] = e.Stop(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	var err error
+	report := new(ShutdownReport)
+	for i, r := range reports {
+		if errs[i] != nil && err == nil {
+			err = errors.Wrapf(errs[i], "failed to stop engine %q", engines[i].Label)
+		}
+		report.Acked += r.Acked
+		report.Nacked += r.Nacked
+		report.Abandoned += r.Abandoned
+	}
+
+	return report, err
+}
@@ -0,0 +1,159 @@
+package subee_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wantedly/subee"
+	subee_testing "github.com/wantedly/subee/testing"
+)
+
+func TestGroup(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		labels = make(map[string]int)
+
+		inFlight, maxInFlight int32
+	)
+
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+
+		mu.Lock()
+		labels[subee.GetLabel(ctx)]++
+		mu.Unlock()
+
+		return nil
+	})
+
+	group := subee.NewGroup(
+		subee.WithMaxConcurrency(1),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	foo, bar := subee_testing.NewFakeSubscriber(), subee_testing.NewFakeSubscriber()
+	group.Add("foo", foo, consumer)
+	group.Add("bar", bar, consumer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- group.Start(context.Background())
+	}()
+
+	var msgs []*subee_testing.FakeMessage
+	for i := 0; i < 3; i++ {
+		for _, s := range []*subee_testing.FakeSubscriber{foo, bar} {
+			m := subee_testing.NewFakeMessage([]byte("foo"), false, false)
+			msgs = append(msgs, m)
+			s.AddMessage(m)
+		}
+	}
+
+	report, err := group.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := report.Abandoned, 0; got != want {
+		t.Errorf("ShutdownReport.Abandoned is %d, want %d", got, want)
+	}
+	for _, m := range msgs {
+		if !m.Acked() {
+			t.Errorf("Message.Acked() is false, want true")
+		}
+	}
+	if got, want := labels["foo"], 3; got != want {
+		t.Errorf("engine foo consumed %d messages, want %d", got, want)
+	}
+	if got, want := labels["bar"], 3; got != want {
+		t.Errorf("engine bar consumed %d messages, want %d", got, want)
+	}
+	if got, want := atomic.LoadInt32(&maxInFlight), int32(1); got != want {
+		t.Errorf("group consumed %d messages concurrently, want %d", got, want)
+	}
+}
+
+func TestGroup_EngineMaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+
+	group := subee.NewGroup(
+		subee.WithMaxConcurrency(4),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+	foo := subee_testing.NewFakeSubscriber()
+	group.Add("foo", foo, consumer, subee.WithMaxConcurrency(1))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- group.Start(context.Background())
+	}()
+
+	for i := 0; i < 4; i++ {
+		foo.AddMessage(subee_testing.NewFakeMessage([]byte("foo"), false, false))
+	}
+
+	if _, err := group.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := atomic.LoadInt32(&maxInFlight), int32(1); got != want {
+		t.Errorf("engine consumed %d messages concurrently, want %d", got, want)
+	}
+}
+
+func TestGroup_WhenEngineFailed(t *testing.T) {
+	group := subee.NewGroup(subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+	foo, bar := subee_testing.NewFakeSubscriber(), subee_testing.NewFakeSubscriber()
+	group.Add("foo", foo, subee.ConsumerFunc(func(context.Context, subee.Message) error { return nil }))
+	group.Add("bar", bar, subee.ConsumerFunc(func(context.Context, subee.Message) error { return nil }))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- group.Start(context.Background())
+	}()
+
+	// Wait for the subscriber to start subscribing.
+	foo.AddMessage(subee_testing.NewFakeMessage([]byte("foo"), false, false))
+	foo.CloseWithError(errors.New("closed"))
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("Start returned nil, want an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the engine failed")
+	}
+}
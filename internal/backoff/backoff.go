@@ -0,0 +1,20 @@
+// Package backoff provides the backoff shared by subscribers to redeliver messages and to reconnect.
+package backoff
+
+import (
+	"math"
+	"time"
+)
+
+// Exponential returns initial doubled n times.
+// The result is capped at max if max is positive, and does not overflow otherwise.
+func Exponential(initial, max time.Duration, n int) time.Duration {
+	d := initial
+	for i := 0; i < n && d > 0 && d <= math.MaxInt64/2 && (max <= 0 || d < max); i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,34 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExponential(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		max  time.Duration
+		n    int
+		want time.Duration
+	}{
+		{name: "initial", max: 5 * time.Second, n: 0, want: time.Second},
+		{name: "doubled", max: 5 * time.Second, n: 2, want: 4 * time.Second},
+		{name: "capped", max: 5 * time.Second, n: 3, want: 5 * time.Second},
+		{name: "capped after many times", max: 5 * time.Second, n: math.MaxInt32, want: 5 * time.Second},
+		{name: "not capped without max", n: 3, want: 8 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Exponential(time.Second, tc.max, tc.n); got != tc.want {
+				t.Errorf("Exponential(1s, %v, %d) is %v, want %v", tc.max, tc.n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExponential_WithoutMax(t *testing.T) {
+	if got := Exponential(time.Second, 0, math.MaxInt32); got <= 0 {
+		t.Errorf("Exponential(1s, 0, MaxInt32) is %v, want positive", got)
+	}
+}
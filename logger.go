@@ -5,3 +5,17 @@ type Logger interface {
 	Printf(format string, v ...interface{})
 	Print(v ...interface{})
 }
+
+// labeledLogger is a Logger that prefixes messages with the label of Engine.
+type labeledLogger struct {
+	Logger
+	prefix string
+}
+
+func (l *labeledLogger) Printf(format string, v ...interface{}) {
+	l.Logger.Printf(l.prefix+format, v...)
+}
+
+func (l *labeledLogger) Print(v ...interface{}) {
+	l.Logger.Print(append([]interface{}{l.prefix}, v...)...)
+}
@@ -1,5 +1,10 @@
 package subee
 
+import (
+	"context"
+	"time"
+)
+
 // Message is an interface of the subscribed message.
 type Message interface {
 	Acknowledger
@@ -12,3 +17,45 @@ type Acknowledger interface {
 	Ack()
 	Nack()
 }
+
+// ResultAcknowledger is an optional interface of Message to send ACK/NACK and report their results.
+// Engine uses it instead of Acknowledger, and reports failed acknowledgements to the StatsHandler and the Logger.
+type ResultAcknowledger interface {
+	AckWithResult() AckResult
+	NackWithResult() AckResult
+}
+
+// AckResult is the result of an acknowledgement.
+type AckResult interface {
+	// Get blocks until the acknowledgement completes or ctx is done, and returns an error if it failed.
+	Get(ctx context.Context) error
+}
+
+// ExtendedMessage is an optional interface of Message to provide the information assigned by the broker.
+// Methods return zero values when the broker does not provide the information.
+type ExtendedMessage interface {
+	Message
+	// MessageID returns the ID of the message assigned by the broker.
+	MessageID() string
+	// PublishTime returns the time when the message was published.
+	PublishTime() time.Time
+	// DeliveryAttempt returns the number of times the message has been delivered, starting from 1.
+	DeliveryAttempt() int
+	// OrderingKey returns the key that orders messages published with it.
+	OrderingKey() string
+}
+
+// earliestPublishTime returns the earliest publish time of the messages implementing ExtendedMessage.
+// It returns zero time when no messages provide their publish time.
+func earliestPublishTime(msgs []Message) (t time.Time) {
+	for _, msg := range msgs {
+		em, ok := msg.(ExtendedMessage)
+		if !ok {
+			continue
+		}
+		if pt := em.PublishTime(); !pt.IsZero() && (t.IsZero() || pt.Before(t)) {
+			t = pt
+		}
+	}
+	return t
+}
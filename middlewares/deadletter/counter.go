@@ -0,0 +1,195 @@
+package subee_deadletter
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wantedly/subee"
+)
+
+// AttemptCounter counts failed deliveries of messages.
+type AttemptCounter interface {
+	// Attempt records a failed delivery of the message and returns the number of deliveries so far.
+	Attempt(subee.Message) int
+	// Forget discards the record of the message.
+	Forget(subee.Message)
+}
+
+// MetadataAttemptCounter returns an AttemptCounter that reads the number of deliveries from the message metadata.
+// It is useful for brokers that track delivery attempts by themselves.
+func MetadataAttemptCounter(key string) AttemptCounter {
+	return metadataAttemptCounter(key)
+}
+
+type metadataAttemptCounter string
+
+func (c metadataAttemptCounter) Attempt(msg subee.Message) int {
+	n, err := strconv.Atoi(msg.Metadata()[string(c)])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+func (c metadataAttemptCounter) Forget(subee.Message) {}
+
+// DeliveryAttemptCounter returns an AttemptCounter that reads the number of deliveries from messages implementing subee.ExtendedMessage.
+// Messages whose delivery attempt is unknown are counted by fallback.
+func DeliveryAttemptCounter(fallback AttemptCounter) AttemptCounter {
+	return &deliveryAttemptCounter{fallback: fallback}
+}
+
+type deliveryAttemptCounter struct {
+	fallback AttemptCounter
+}
+
+func (c *deliveryAttemptCounter) Attempt(msg subee.Message) int {
+	if em, ok := msg.(subee.ExtendedMessage); ok {
+		if n := em.DeliveryAttempt(); n > 0 {
+			return n
+		}
+	}
+	return c.fallback.Attempt(msg)
+}
+
+func (c *deliveryAttemptCounter) Forget(msg subee.Message) {
+	c.fallback.Forget(msg)
+}
+
+// KeyFunc returns the key to identify redelivered messages.
+type KeyFunc func(subee.Message) string
+
+const (
+	// DefaultCounterTTL is the default duration for which the in-memory counter keeps a message not redelivered.
+	DefaultCounterTTL = time.Hour
+
+	// DefaultCounterMaxEntries is the default maximum number of messages kept by the in-memory counter.
+	DefaultCounterMaxEntries = 10000
+)
+
+// CounterOption configures the AttemptCounter returned by NewInMemoryAttemptCounter.
+type CounterOption func(*inMemoryAttemptCounter)
+
+// WithCounterTTL returns a CounterOption that sets the duration for which a message is kept after its last failed delivery.
+func WithCounterTTL(d time.Duration) CounterOption {
+	return func(c *inMemoryAttemptCounter) {
+		if d > 0 {
+			c.ttl = d
+		}
+	}
+}
+
+// WithCounterMaxEntries returns a CounterOption that sets the maximum number of messages kept.
+// The message least recently failed is discarded when the limit is exceeded.
+func WithCounterMaxEntries(n int) CounterOption {
+	return func(c *inMemoryAttemptCounter) {
+		if n > 0 {
+			c.maxEntries = n
+		}
+	}
+}
+
+// NewInMemoryAttemptCounter returns an AttemptCounter that counts deliveries in memory.
+// Messages are identified by keyFunc if it is set.
+// Otherwise they are identified by their ID if they implement subee.ExtendedMessage, or by their payload.
+// Note that identifying by payload merges the counts of distinct messages with the same payload.
+//
+// A message is discarded when it is not redelivered within DefaultCounterTTL,
+// or when more than DefaultCounterMaxEntries messages are kept,
+// so that messages settled elsewhere, such as on another replica, do not stay in memory forever.
+func NewInMemoryAttemptCounter(keyFunc KeyFunc, opts ...CounterOption) AttemptCounter {
+	if keyFunc == nil {
+		keyFunc = messageKey
+	}
+	c := &inMemoryAttemptCounter{
+		keyFunc:    keyFunc,
+		ttl:        DefaultCounterTTL,
+		maxEntries: DefaultCounterMaxEntries,
+		now:        time.Now,
+		attempts:   make(map[string]*list.Element),
+		order:      list.New(),
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+type inMemoryAttemptCounter struct {
+	keyFunc    KeyFunc
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]*list.Element
+	// order holds *attemptEntry from the most recently failed one.
+	order *list.List
+}
+
+type attemptEntry struct {
+	key       string
+	count     int
+	expiresAt time.Time
+}
+
+func (c *inMemoryAttemptCounter) Attempt(msg subee.Message) int {
+	key := c.keyFunc(msg)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(now)
+
+	elem, ok := c.attempts[key]
+	if !ok {
+		elem = c.order.PushFront(&attemptEntry{key: key})
+		c.attempts[key] = elem
+	}
+	c.order.MoveToFront(elem)
+
+	e := elem.Value.(*attemptEntry)
+	e.count++
+	e.expiresAt = now.Add(c.ttl)
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+
+	return e.count
+}
+
+func (c *inMemoryAttemptCounter) Forget(msg subee.Message) {
+	key := c.keyFunc(msg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.attempts[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// expire discards messages not redelivered within the TTL.
+// Entries expire in the reverse order of the list because the TTL is the same for all of them.
+func (c *inMemoryAttemptCounter) expire(now time.Time) {
+	for elem := c.order.Back(); elem != nil && !now.Before(elem.Value.(*attemptEntry).expiresAt); elem = c.order.Back() {
+		c.remove(elem)
+	}
+}
+
+func (c *inMemoryAttemptCounter) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.attempts, elem.Value.(*attemptEntry).key)
+}
+
+func messageKey(msg subee.Message) string {
+	if em, ok := msg.(subee.ExtendedMessage); ok && em.MessageID() != "" {
+		return "id:" + em.MessageID()
+	}
+	sum := sha256.Sum256(msg.Data())
+	return hex.EncodeToString(sum[:])
+}
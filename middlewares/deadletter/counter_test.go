@@ -0,0 +1,48 @@
+package subee_deadletter
+
+import (
+	"testing"
+	"time"
+
+	message_testing "github.com/wantedly/subee/testing"
+)
+
+func TestInMemoryAttemptCounter(t *testing.T) {
+	now := time.Now()
+	c := NewInMemoryAttemptCounter(nil, WithCounterTTL(time.Minute), WithCounterMaxEntries(2)).(*inMemoryAttemptCounter)
+	c.now = func() time.Time { return now }
+
+	foo := message_testing.NewFakeMessage([]byte("foo"), false, false)
+	bar := message_testing.NewFakeMessage([]byte("bar"), false, false)
+	baz := message_testing.NewFakeMessage([]byte("baz"), false, false)
+
+	attempt := func(msg *message_testing.FakeMessage, want int) {
+		t.Helper()
+		if got := c.Attempt(msg); got != want {
+			t.Errorf("Attempt(%q) returned %d, want %d", msg.Data(), got, want)
+		}
+	}
+
+	attempt(foo, 1)
+	attempt(foo, 2)
+
+	c.Forget(foo)
+	attempt(foo, 1)
+
+	now = now.Add(30 * time.Second)
+	attempt(bar, 1)
+
+	// foo is expired while bar is kept.
+	now = now.Add(45 * time.Second)
+	attempt(foo, 1)
+	attempt(bar, 2)
+
+	// foo is discarded because it is the least recently failed one.
+	attempt(baz, 1)
+	attempt(bar, 3)
+	attempt(foo, 1)
+
+	if got, want := len(c.attempts), 2; got != want {
+		t.Errorf("The counter keeps %d messages, want %d", got, want)
+	}
+}
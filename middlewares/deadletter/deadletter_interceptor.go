@@ -0,0 +1,119 @@
+package subee_deadletter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// ConsumerInterceptor returns a new consumer interceptor to forward poison messages to the sink.
+// The message is acked once it is published to the sink.
+func ConsumerInterceptor(sink Sink, opts ...Option) subee.ConsumerInterceptor {
+	cfg := createConfig(opts)
+
+	return func(consumer subee.Consumer) subee.Consumer {
+		return subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			err := consumer.Consume(ctx, msg)
+			if err == nil {
+				cfg.AttemptCounter.Forget(msg)
+				return nil
+			}
+
+			return errors.WithStack(cfg.handleError(ctx, sink, msg, err))
+		})
+	}
+}
+
+// BatchConsumerInterceptor returns a new batch consumer interceptor to forward poison messages to the sink.
+// The messages published to the sink are acked, and the others failed are nacked.
+func BatchConsumerInterceptor(sink Sink, opts ...Option) subee.BatchConsumerInterceptor {
+	cfg := createConfig(opts)
+
+	return func(consumer subee.BatchConsumer) subee.BatchConsumer {
+		return subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+			err := consumer.BatchConsume(ctx, msgs)
+			if err == nil {
+				for _, msg := range msgs {
+					cfg.AttemptCounter.Forget(msg)
+				}
+				return nil
+			}
+
+			var batchErr subee.BatchError
+			partial := errors.As(err, &batchErr)
+
+			failed := make(subee.BatchError)
+			for i, msg := range msgs {
+				msgErr := err
+				if partial {
+					msgErr = batchErr[i]
+				}
+				if msgErr == nil {
+					cfg.AttemptCounter.Forget(msg)
+					continue
+				}
+				if msgErr = cfg.handleError(ctx, sink, msg, msgErr); msgErr != nil {
+					failed[i] = msgErr
+				}
+			}
+
+			if len(failed) == 0 {
+				return nil
+			}
+			if !partial && len(failed) == len(msgs) {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(failed)
+		})
+	}
+}
+
+func createConfig(opts []Option) *Config {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+	if cfg.AttemptCounter == nil {
+		cfg.AttemptCounter = DeliveryAttemptCounter(NewInMemoryAttemptCounter(nil))
+	}
+	return cfg
+}
+
+// handleError publishes the message to the sink when it has been failed too many times or permanently.
+// It returns nil if the message is dead-lettered, otherwise it returns err.
+func (c *Config) handleError(ctx context.Context, sink Sink, msg subee.Message, err error) error {
+	if subee.IsSkip(err) {
+		c.AttemptCounter.Forget(msg)
+		return err
+	}
+
+	attempts := c.AttemptCounter.Attempt(msg)
+	if attempts < c.MaxDeliveries && !subee.IsPermanent(err) {
+		return err
+	}
+
+	if pubErr := sink.Publish(ctx, newLetter(msg, err, attempts)); pubErr != nil {
+		return errors.Wrapf(err, "failed to publish the message to dead-letter sink: %v", pubErr)
+	}
+	c.AttemptCounter.Forget(msg)
+
+	return nil
+}
+
+// DecodeErrorHandler returns a subee.DecodeErrorHandler that publishes messages failed to be decoded to the sink.
+// The message is acked once it is published to the sink.
+func DecodeErrorHandler(sink Sink) subee.DecodeErrorHandler {
+	return func(ctx context.Context, msg subee.Message, err error) error {
+		if pubErr := sink.Publish(ctx, newLetter(msg, err, 1)); pubErr != nil {
+			return errors.Wrapf(err, "failed to publish the message to dead-letter sink: %v", pubErr)
+		}
+		return nil
+	}
+}
+
+// Forward returns a subee.Consumer that publishes messages to the sink with the reason.
+// It is useful as the fallback handler of subee.Router to dead-letter unmatched messages.
+func Forward(sink Sink, reason error) subee.Consumer {
+	return subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		return errors.WithStack(sink.Publish(ctx, newLetter(msg, reason, 1)))
+	})
+}
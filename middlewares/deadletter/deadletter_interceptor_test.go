@@ -0,0 +1,249 @@
+package subee_deadletter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+	message_testing "github.com/wantedly/subee/testing"
+)
+
+type metadataMessage struct {
+	subee.Message
+	metadata map[string]string
+}
+
+func (m *metadataMessage) Metadata() map[string]string { return m.metadata }
+
+func TestConsumerInterceptor(t *testing.T) {
+	sink := NewMemorySink()
+	consumer := ConsumerInterceptor(sink, WithMaxDeliveries(3))(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			return errors.New("error")
+		}),
+	)
+	msg := message_testing.NewFakeMessage([]byte("foo"), false, false)
+
+	for i := 1; i < 3; i++ {
+		if err := consumer.Consume(context.Background(), msg); err == nil {
+			t.Errorf("Consume() returned nil at %d attempts, want an error", i)
+		}
+	}
+	if got, want := len(sink.Letters()), 0; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+
+	if err := consumer.Consume(context.Background(), msg); err != nil {
+		t.Errorf("Consume() returned %v, want nil", err)
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 1; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	if got, want := string(letters[0].Data), "foo"; got != want {
+		t.Errorf("Letter.Data is %q, want %q", got, want)
+	}
+	if got, want := letters[0].Attempts, 3; got != want {
+		t.Errorf("Letter.Attempts is %d, want %d", got, want)
+	}
+	if got, want := letters[0].Error, "error"; got != want {
+		t.Errorf("Letter.Error is %q, want %q", got, want)
+	}
+}
+
+func TestConsumerInterceptor_WithMetadataAttemptCounter(t *testing.T) {
+	sink := NewMemorySink()
+	consumer := ConsumerInterceptor(sink, WithMaxDeliveries(3), WithAttemptCounter(MetadataAttemptCounter("attempt")))(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			return errors.New("error")
+		}),
+	)
+
+	tests := []struct {
+		attempt string
+		err     bool
+	}{
+		{attempt: "", err: true},
+		{attempt: "2", err: true},
+		{attempt: "3", err: false},
+	}
+
+	for _, test := range tests {
+		msg := &metadataMessage{
+			Message:  message_testing.NewFakeMessage([]byte("foo"), false, false),
+			metadata: map[string]string{"attempt": test.attempt},
+		}
+		err := consumer.Consume(context.Background(), msg)
+		if got, want := err != nil, test.err; got != want {
+			t.Errorf("Consume() returned %v at attempt %q, want error: %t", err, test.attempt, want)
+		}
+	}
+
+	if got, want := len(sink.Letters()), 1; got != want {
+		t.Errorf("%d letters published, want %d", got, want)
+	}
+}
+
+func TestConsumerInterceptor_WithExtendedMessage(t *testing.T) {
+	sink := NewMemorySink()
+	consumer := ConsumerInterceptor(sink, WithMaxDeliveries(3))(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			return errors.New("error")
+		}),
+	)
+
+	tests := []struct {
+		id      string
+		attempt int
+		err     bool
+	}{
+		// The delivery attempt reported by the broker is preferred.
+		{id: "1", attempt: 1, err: true},
+		{id: "1", attempt: 3, err: false},
+		// Messages without the delivery attempt are counted by their ID, not by their payload.
+		{id: "2", err: true},
+		{id: "3", err: true},
+		{id: "2", err: true},
+		{id: "2", err: false},
+	}
+
+	for i, test := range tests {
+		msg := message_testing.NewFakeExtendedMessage([]byte("foo"), test.id, time.Time{}, test.attempt, "")
+		err := consumer.Consume(context.Background(), msg)
+		if got, want := err != nil, test.err; got != want {
+			t.Errorf("Consume() returned %v at tests[%d], want error: %t", err, i, want)
+		}
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 2; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	for i, want := range []string{"1", "2"} {
+		if got := letters[i].ID; got != want {
+			t.Errorf("Letters[%d].ID is %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestBatchConsumerInterceptor(t *testing.T) {
+	sink := NewMemorySink()
+	counter := MetadataAttemptCounter("attempt")
+
+	msgs := []subee.Message{
+		&metadataMessage{
+			Message:  message_testing.NewFakeMessage([]byte("foo"), false, false),
+			metadata: map[string]string{"attempt": "3"},
+		},
+		&metadataMessage{
+			Message:  message_testing.NewFakeMessage([]byte("bar"), false, false),
+			metadata: map[string]string{"attempt": "1"},
+		},
+		&metadataMessage{
+			Message:  message_testing.NewFakeMessage([]byte("baz"), false, false),
+			metadata: map[string]string{"attempt": "3"},
+		},
+	}
+
+	err := BatchConsumerInterceptor(sink, WithMaxDeliveries(3), WithAttemptCounter(counter))(
+		subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+			return subee.BatchError{0: errors.New("error"), 1: errors.New("error")}
+		}),
+	).BatchConsume(context.Background(), msgs)
+
+	var batchErr subee.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchConsume() returned %v, want subee.BatchError", err)
+	}
+	if got, want := len(batchErr), 1; got != want {
+		t.Errorf("BatchConsume() returned %d errors, want %d", got, want)
+	}
+	if batchErr[1] == nil {
+		t.Error("BatchConsume() returned nil for message[1], want an error")
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 1; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	if got, want := string(letters[0].Data), "foo"; got != want {
+		t.Errorf("Letter.Data is %q, want %q", got, want)
+	}
+}
+
+func TestConsumerInterceptor_WhenPermanentError(t *testing.T) {
+	sink := NewMemorySink()
+	consumer := ConsumerInterceptor(sink, WithMaxDeliveries(3))(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			if string(msg.Data()) == "skip" {
+				return subee.Skip
+			}
+			return subee.Permanent(errors.New("error"))
+		}),
+	)
+
+	if err := consumer.Consume(context.Background(), message_testing.NewFakeMessage([]byte("skip"), false, false)); !subee.IsSkip(err) {
+		t.Errorf("Consume() returned %v, want subee.Skip", err)
+	}
+	if err := consumer.Consume(context.Background(), message_testing.NewFakeMessage([]byte("foo"), false, false)); err != nil {
+		t.Errorf("Consume() returned %v, want nil", err)
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 1; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	if got, want := letters[0].Attempts, 1; got != want {
+		t.Errorf("Letter.Attempts is %d, want %d", got, want)
+	}
+}
+
+func TestDecodeErrorHandler(t *testing.T) {
+	sink := NewMemorySink()
+	consumer := subee.NewTyped(
+		subee.JSONCodec[map[string]string](),
+		subee.TypedConsumerFunc[map[string]string](func(ctx context.Context, obj map[string]string) error {
+			t.Error("consumer should not be called")
+			return nil
+		}),
+		subee.WithDecodeErrorHandler(DecodeErrorHandler(sink)),
+	)
+
+	if err := consumer.Consume(context.Background(), message_testing.NewFakeMessage([]byte("{"), false, false)); err != nil {
+		t.Errorf("Consume() returned %v, want nil", err)
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 1; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	if got, want := string(letters[0].Data), "{"; got != want {
+		t.Errorf("Letter.Data is %q, want %q", got, want)
+	}
+}
+
+func TestForward(t *testing.T) {
+	sink := NewMemorySink()
+
+	router := subee.NewRouter()
+	router.Fallback(Forward(sink, subee.ErrUnmatched))
+
+	msg := message_testing.NewFakeMessageWithMetadata([]byte("foo"), map[string]string{"event_type": "unknown"})
+	if err := router.Consume(context.Background(), msg); err != nil {
+		t.Errorf("Consume() returned %v, want nil", err)
+	}
+
+	letters := sink.Letters()
+	if got, want := len(letters), 1; got != want {
+		t.Fatalf("%d letters published, want %d", got, want)
+	}
+	if got, want := letters[0].Error, subee.ErrUnmatched.Error(); got != want {
+		t.Errorf("Letter.Error is %q, want %q", got, want)
+	}
+	if got, want := letters[0].Metadata["event_type"], "unknown"; got != want {
+		t.Errorf("Letter.Metadata[event_type] is %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/wantedly/subee/middlewares/deadletter
+
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+)
+
+replace github.com/wantedly/subee => ../..
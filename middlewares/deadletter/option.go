@@ -0,0 +1,44 @@
+package subee_deadletter
+
+// DefaultMaxDeliveries is the default number of failed deliveries before a message is dead-lettered.
+const DefaultMaxDeliveries = 5
+
+// Config represents the dead-letter configuration.
+type Config struct {
+	MaxDeliveries  int
+	AttemptCounter AttemptCounter
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// newDefaultConfig returns the *Config with default value set.
+func newDefaultConfig() *Config {
+	return &Config{
+		MaxDeliveries: DefaultMaxDeliveries,
+	}
+}
+
+// Option configures Config.
+type Option func(*Config)
+
+// WithMaxDeliveries returns an Option that sets the number of failed deliveries before a message is dead-lettered.
+func WithMaxDeliveries(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.MaxDeliveries = n
+		}
+	}
+}
+
+// WithAttemptCounter returns an Option that sets the AttemptCounter implementation.
+// By default, the delivery attempt of messages implementing subee.ExtendedMessage is used,
+// and the other messages are counted in memory.
+func WithAttemptCounter(counter AttemptCounter) Option {
+	return func(c *Config) {
+		c.AttemptCounter = counter
+	}
+}
@@ -0,0 +1,106 @@
+package subee_deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// Letter represents a message that could not be consumed.
+type Letter struct {
+	ID       string            `json:"id,omitempty"`
+	Data     []byte            `json:"data"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Error    string            `json:"error"`
+	Attempts int               `json:"attempts"`
+	FailedAt time.Time         `json:"failed_at"`
+}
+
+func newLetter(msg subee.Message, err error, attempts int) *Letter {
+	l := &Letter{
+		Data:     msg.Data(),
+		Metadata: msg.Metadata(),
+		Error:    err.Error(),
+		Attempts: attempts,
+		FailedAt: time.Now().UTC(),
+	}
+	if em, ok := msg.(subee.ExtendedMessage); ok {
+		l.ID = em.MessageID()
+	}
+	return l
+}
+
+// Sink is the interface to publish dead letters.
+type Sink interface {
+	Publish(context.Context, *Letter) error
+}
+
+// FileSink is a Sink implementation that writes letters as JSON Lines.
+type FileSink struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+}
+
+// NewFileSink creates a new FileSink instance writing to w.
+func NewFileSink(w io.Writer) *FileSink {
+	return &FileSink{enc: json.NewEncoder(w)}
+}
+
+// OpenFileSink creates a new FileSink instance appending to the named file.
+func OpenFileSink(name string) (*FileSink, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open dead-letter file")
+	}
+	s := NewFileSink(f)
+	s.closer = f
+	return s, nil
+}
+
+// Publish implements Sink.Publish.
+func (s *FileSink) Publish(ctx context.Context, l *Letter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.WithStack(s.enc.Encode(l))
+}
+
+// Close closes the underlying file opened by OpenFileSink.
+func (s *FileSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return errors.WithStack(s.closer.Close())
+}
+
+// MemorySink is a Sink implementation that keeps letters in memory.
+type MemorySink struct {
+	mu      sync.Mutex
+	letters []*Letter
+}
+
+// NewMemorySink creates a new MemorySink instance.
+func NewMemorySink() *MemorySink {
+	return new(MemorySink)
+}
+
+// Publish implements Sink.Publish.
+func (s *MemorySink) Publish(ctx context.Context, l *Letter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, l)
+	return nil
+}
+
+// Letters returns the published letters.
+func (s *MemorySink) Letters() []*Letter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Letter(nil), s.letters...)
+}
@@ -0,0 +1,40 @@
+package subee_deadletter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	buf := new(bytes.Buffer)
+	sink := NewFileSink(buf)
+
+	in := []*Letter{
+		{Data: []byte("foo"), Error: "error", Attempts: 3, FailedAt: time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{Data: []byte("bar"), Metadata: map[string]string{"id": "1"}, Error: "error", Attempts: 5, FailedAt: time.Date(2019, 10, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, l := range in {
+		if err := sink.Publish(context.Background(), l); err != nil {
+			t.Fatalf("Publish() returned %v, want nil", err)
+		}
+	}
+
+	dec := json.NewDecoder(buf)
+	for i, want := range in {
+		got := new(Letter)
+		if err := dec.Decode(got); err != nil {
+			t.Fatalf("failed to decode line %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("line %d is %+v, want %+v", i, got, want)
+		}
+	}
+	if dec.More() {
+		t.Error("extra lines written")
+	}
+}
@@ -1,14 +1,19 @@
 module github.com/wantedly/subee/middlewares/logging/zap
 
-go 1.11
+go 1.21
+
+require (
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+	go.uber.org/zap v1.10.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/pkg/errors v0.8.1
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.2.2 // indirect
-	github.com/wantedly/subee v0.5.0
 	go.uber.org/atomic v1.3.2 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
-	go.uber.org/zap v1.10.0
 )
+
+replace github.com/wantedly/subee => ../../..
@@ -20,14 +20,15 @@ func ConsumerInterceptor(logger *zap.Logger) subee.ConsumerInterceptor {
 	return func(consumer subee.Consumer) subee.Consumer {
 		return subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
 			msgCnt := 1
+			fields := messageFields(msg)
 
-			startConsume(logger, msgCnt)
+			startConsume(logger, msgCnt, fields...)
 
 			startTime := time.Now()
 
 			err := consumer.Consume(ctx, msg)
 
-			endConsume(logger, since(startTime), msgCnt, err)
+			endConsume(logger, since(startTime), msgCnt, err, fields...)
 
 			return errors.WithStack(err)
 		})
@@ -51,21 +52,43 @@ func BatchConsumerInterceptor(logger *zap.Logger) subee.BatchConsumerInterceptor
 	}
 }
 
-func startConsume(logger *zap.Logger, msgCnt int) {
+func startConsume(logger *zap.Logger, msgCnt int, fields ...zap.Field) {
 	logger.Info(
 		"Start consume message.",
-		zap.Int("message_count", msgCnt),
+		append([]zap.Field{zap.Int("message_count", msgCnt)}, fields...)...,
 	)
 }
 
-func endConsume(logger *zap.Logger, d time.Duration, msgCnt int, err error) {
+func endConsume(logger *zap.Logger, d time.Duration, msgCnt int, err error, fields ...zap.Field) {
 	logger.Check(level(err), "End consume message.").Write(
-		zap.Error(err),
-		zap.Int("message_count", msgCnt),
-		zap.Duration("time", d),
+		append([]zap.Field{
+			zap.Error(err),
+			zap.Int("message_count", msgCnt),
+			zap.Duration("time", d),
+		}, fields...)...,
 	)
 }
 
+// messageFields returns the fields of the information assigned by the broker if msg implements subee.ExtendedMessage.
+func messageFields(msg subee.Message) []zap.Field {
+	em, ok := msg.(subee.ExtendedMessage)
+	if !ok {
+		return nil
+	}
+
+	var fields []zap.Field
+	if id := em.MessageID(); id != "" {
+		fields = append(fields, zap.String("message_id", id))
+	}
+	if n := em.DeliveryAttempt(); n > 0 {
+		fields = append(fields, zap.Int("delivery_attempt", n))
+	}
+	if key := em.OrderingKey(); key != "" {
+		fields = append(fields, zap.String("ordering_key", key))
+	}
+	return fields
+}
+
 func level(err error) zapcore.Level {
 	if err != nil {
 		return zap.ErrorLevel
@@ -62,6 +62,36 @@ func TestConsumerInterceptor(t *testing.T) {
 	}
 }
 
+func TestConsumerInterceptor_WithExtendedMessage(t *testing.T) {
+	since = dummySince()
+
+	defer func() {
+		since = func(t time.Time) time.Duration {
+			return time.Since(t)
+		}
+	}()
+
+	buf := &bytes.Buffer{}
+	logger := dummyLogger(buf)
+
+	want := `{"level":"INFO","msg":"Start consume message.","message_count":1,"message_id":"123","delivery_attempt":2,"ordering_key":"foo"}
+{"level":"INFO","msg":"End consume message.","message_count":1,"time":"0s","message_id":"123","delivery_attempt":2,"ordering_key":"foo"}
+`
+
+	ConsumerInterceptor(logger)(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			return nil
+		}),
+	).Consume(
+		context.Background(),
+		message_testing.NewFakeExtendedMessage(nil, "123", time.Time{}, 2, "foo"),
+	)
+
+	if got := buf.String(); got != want {
+		t.Errorf("\nwant:\n%sgot:\n%s", want, got)
+	}
+}
+
 func TestBatchConsumerInterceptor(t *testing.T) {
 	since = dummySince()
 
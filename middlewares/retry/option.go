@@ -0,0 +1,108 @@
+package subee_retry
+
+import (
+	"time"
+
+	"github.com/wantedly/subee"
+)
+
+const (
+	// DefaultMaxAttempts is the default maximum number of attempts including the first one.
+	DefaultMaxAttempts = 3
+
+	// DefaultInitialInterval is the default interval before the first retry.
+	DefaultInitialInterval = 100 * time.Millisecond
+
+	// DefaultMaxInterval is the default upper bound of the interval between retries.
+	DefaultMaxInterval = 10 * time.Second
+
+	// DefaultMultiplier is the default factor to increase the interval per retry.
+	DefaultMultiplier = 2.0
+
+	// DefaultJitter is the default randomization factor of the interval.
+	DefaultJitter = 0.2
+)
+
+// RetryableFunc reports whether the consumption failed with err should be retried.
+type RetryableFunc func(err error) bool
+
+// Config represents the retry configuration.
+type Config struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	Retryable       RetryableFunc
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// newDefaultConfig returns the *Config with default value set.
+func newDefaultConfig() *Config {
+	return &Config{
+		MaxAttempts:     DefaultMaxAttempts,
+		InitialInterval: DefaultInitialInterval,
+		MaxInterval:     DefaultMaxInterval,
+		Multiplier:      DefaultMultiplier,
+		Jitter:          DefaultJitter,
+		Retryable:       subee.IsRetryable,
+	}
+}
+
+// Option configures Config.
+type Option func(*Config)
+
+// WithMaxAttempts returns an Option that sets the maximum number of attempts including the first one.
+func WithMaxAttempts(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.MaxAttempts = n
+		}
+	}
+}
+
+// WithBackoff returns an Option that sets the interval before the first retry and its upper bound.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		if initial > 0 {
+			c.InitialInterval = initial
+		}
+		if max > 0 {
+			c.MaxInterval = max
+		}
+	}
+}
+
+// WithMultiplier returns an Option that sets the factor to increase the interval per retry.
+func WithMultiplier(m float64) Option {
+	return func(c *Config) {
+		if m >= 1 {
+			c.Multiplier = m
+		}
+	}
+}
+
+// WithJitter returns an Option that sets the randomization factor of the interval.
+// e.g) 0.2 randomizes the interval within ±20%.
+func WithJitter(j float64) Option {
+	return func(c *Config) {
+		if j >= 0 && j <= 1 {
+			c.Jitter = j
+		}
+	}
+}
+
+// WithRetryable returns an Option that sets the function to classify retryable errors.
+// By default, errors except subee.Permanent and subee.Skip are retried.
+func WithRetryable(f RetryableFunc) Option {
+	return func(c *Config) {
+		if f != nil {
+			c.Retryable = f
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package subee_retry
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// ConsumerInterceptor returns a new consumer interceptor to retry failed consumption.
+func ConsumerInterceptor(opts ...Option) subee.ConsumerInterceptor {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	return func(consumer subee.Consumer) subee.Consumer {
+		return subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			var err error
+			for attempt := 1; ; attempt++ {
+				err = consumer.Consume(ctx, msg)
+				if err == nil || !cfg.Retryable(err) || !cfg.wait(ctx, attempt) {
+					break
+				}
+			}
+			return errors.WithStack(err)
+		})
+	}
+}
+
+// BatchConsumerInterceptor returns a new batch consumer interceptor to retry failed consumption.
+// When subee.BatchError is returned, only the failed messages are retried.
+func BatchConsumerInterceptor(opts ...Option) subee.BatchConsumerInterceptor {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	return func(consumer subee.BatchConsumer) subee.BatchConsumer {
+		return subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+			var (
+				err     error
+				partial bool
+				pending = make([]int, len(msgs))
+				failed  = make(subee.BatchError)
+			)
+			for i := range msgs {
+				pending[i] = i
+			}
+
+			for attempt := 1; len(pending) > 0; attempt++ {
+				batch := make([]subee.Message, len(pending))
+				for i, idx := range pending {
+					batch[i] = msgs[idx]
+				}
+
+				err = consumer.BatchConsume(ctx, batch)
+				if err == nil {
+					break
+				}
+
+				errs := make(map[int]error, len(pending))
+				var batchErr subee.BatchError
+				if errors.As(err, &batchErr) {
+					partial = true
+					for i, idx := range pending {
+						if batchErr[i] != nil {
+							errs[idx] = batchErr[i]
+						}
+					}
+				} else {
+					for _, idx := range pending {
+						errs[idx] = err
+					}
+				}
+
+				retries := make([]int, 0, len(errs))
+				for _, idx := range pending {
+					if e, ok := errs[idx]; ok {
+						if cfg.Retryable(e) {
+							retries = append(retries, idx)
+						} else {
+							failed[idx] = e
+						}
+					}
+				}
+				pending = retries
+
+				if len(pending) > 0 && !cfg.wait(ctx, attempt) {
+					for _, idx := range pending {
+						failed[idx] = errs[idx]
+					}
+					break
+				}
+			}
+
+			if len(failed) == 0 {
+				return nil
+			}
+			if !partial {
+				return errors.WithStack(err)
+			}
+			return errors.WithStack(failed)
+		})
+	}
+}
+
+// wait sleeps before the next attempt.
+// It returns false when no more attempts are allowed or ctx is done.
+func (c *Config) wait(ctx context.Context, attempt int) bool {
+	if attempt >= c.MaxAttempts {
+		return false
+	}
+
+	t := time.NewTimer(c.backoff(attempt))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// backoff returns the interval before the next attempt with exponential backoff and jitter.
+func (c *Config) backoff(attempt int) time.Duration {
+	d := float64(c.InitialInterval) * math.Pow(c.Multiplier, float64(attempt-1))
+	if max := float64(c.MaxInterval); d > max {
+		d = max
+	}
+	if c.Jitter > 0 {
+		d += d * c.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,198 @@
+package subee_retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+	message_testing "github.com/wantedly/subee/testing"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestConsumerInterceptor(t *testing.T) {
+	tests := []struct {
+		test     string
+		failures int
+		opts     []Option
+		attempts int
+		err      bool
+	}{
+		{
+			test:     "succeeded after retries",
+			failures: 2,
+			opts:     []Option{WithMaxAttempts(3)},
+			attempts: 3,
+		},
+		{
+			test:     "failed when attempts exceeded",
+			failures: 3,
+			opts:     []Option{WithMaxAttempts(3)},
+			attempts: 3,
+			err:      true,
+		},
+		{
+			test:     "failed when not retryable",
+			failures: 3,
+			opts:     []Option{WithRetryable(func(err error) bool { return false })},
+			attempts: 1,
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.test, func(t *testing.T) {
+			var attempts int
+
+			opts := append([]Option{WithBackoff(time.Millisecond, time.Millisecond)}, test.opts...)
+			err := ConsumerInterceptor(opts...)(
+				subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+					attempts++
+					if attempts <= test.failures {
+						return errTemporary
+					}
+					return nil
+				}),
+			).Consume(
+				context.Background(),
+				message_testing.NewFakeMessage(nil, false, false),
+			)
+
+			if got, want := err != nil, test.err; got != want {
+				t.Errorf("Consume() returned %v, want error: %t", err, want)
+			}
+			if got, want := attempts, test.attempts; got != want {
+				t.Errorf("Consume() called %d times, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestConsumerInterceptor_WhenContextCanceled(t *testing.T) {
+	var attempts int
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := ConsumerInterceptor(WithMaxAttempts(5), WithBackoff(time.Hour, time.Hour))(
+		subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			attempts++
+			cancel()
+			return errTemporary
+		}),
+	).Consume(
+		ctx,
+		message_testing.NewFakeMessage(nil, false, false),
+	)
+
+	if err == nil {
+		t.Error("Consume() returned nil, want an error")
+	}
+	if got, want := attempts, 1; got != want {
+		t.Errorf("Consume() called %d times, want %d", got, want)
+	}
+}
+
+func TestBatchConsumerInterceptor(t *testing.T) {
+	var calls [][]subee.Message
+
+	msgs := []subee.Message{
+		message_testing.NewFakeMessage([]byte("foo"), false, false),
+		message_testing.NewFakeMessage([]byte("bar"), false, false),
+		message_testing.NewFakeMessage([]byte("baz"), false, false),
+	}
+	errPermanent := errors.New("permanent")
+
+	err := BatchConsumerInterceptor(
+		WithMaxAttempts(3),
+		WithBackoff(time.Millisecond, time.Millisecond),
+		WithRetryable(func(err error) bool { return err != errPermanent }),
+	)(
+		subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+			calls = append(calls, msgs)
+			if len(calls) == 1 {
+				return subee.BatchError{1: errTemporary, 2: errPermanent}
+			}
+			return nil
+		}),
+	).BatchConsume(context.Background(), msgs)
+
+	var batchErr subee.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchConsume() returned %v, want subee.BatchError", err)
+	}
+	if got, want := len(batchErr), 1; got != want {
+		t.Errorf("BatchConsume() returned %d errors, want %d", got, want)
+	}
+	if got, want := batchErr[2], errPermanent; got != want {
+		t.Errorf("BatchConsume() returned %v for message[2], want %v", got, want)
+	}
+
+	if got, want := len(calls), 2; got != want {
+		t.Fatalf("BatchConsume() called %d times, want %d", got, want)
+	}
+	if got, want := len(calls[1]), 1; got != want {
+		t.Fatalf("BatchConsume() retried %d messages, want %d", got, want)
+	}
+	if got, want := calls[1][0], msgs[1]; got != want {
+		t.Errorf("BatchConsume() retried %v, want %v", got, want)
+	}
+}
+
+func TestBatchConsumerInterceptor_WhenError(t *testing.T) {
+	var attempts int
+
+	err := BatchConsumerInterceptor(WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))(
+		subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+			attempts++
+			return errTemporary
+		}),
+	).BatchConsume(
+		context.Background(),
+		[]subee.Message{message_testing.NewFakeMessage(nil, false, false)},
+	)
+
+	if got, want := errors.Cause(err), errTemporary; got != want {
+		t.Errorf("BatchConsume() returned %v, want %v", got, want)
+	}
+	if got, want := attempts, 2; got != want {
+		t.Errorf("BatchConsume() called %d times, want %d", got, want)
+	}
+}
+
+func TestConfig_backoff(t *testing.T) {
+	cfg := newDefaultConfig()
+	WithBackoff(10*time.Millisecond, 50*time.Millisecond)(cfg)
+	WithJitter(0)(cfg)
+
+	for attempt, want := range []time.Duration{10, 20, 40, 50, 50} {
+		if got, want := cfg.backoff(attempt+1), want*time.Millisecond; got != want {
+			t.Errorf("backoff(%d) returned %v, want %v", attempt+1, got, want)
+		}
+	}
+}
+
+func TestConsumerInterceptor_WhenPermanentError(t *testing.T) {
+	for _, err := range []error{subee.Permanent(errTemporary), subee.Skip} {
+		var attempts int
+
+		got := ConsumerInterceptor(WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond))(
+			subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+				attempts++
+				return err
+			}),
+		).Consume(
+			context.Background(),
+			message_testing.NewFakeMessage(nil, false, false),
+		)
+
+		if subee.IsRetryable(got) {
+			t.Errorf("Consume() returned a retryable error %v, want %v", got, err)
+		}
+		if got, want := attempts, 1; got != want {
+			t.Errorf("Consume() called %d times with %v, want %d", got, err, want)
+		}
+	}
+}
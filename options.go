@@ -61,3 +61,66 @@ func WithAckImmediately() Option {
 		c.AckImmediately = true
 	}
 }
+
+// WithMaxConcurrency returns an Option that sets the maximum number of consumptions running concurrently.
+// Receiving further messages is blocked until a running consumption finishes.
+func WithMaxConcurrency(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.MaxConcurrency = n
+		}
+	}
+}
+
+// WithShutdownTimeout returns an Option that sets the maximum time to wait for consuming received messages on shutdown.
+// Messages whose consumption has not finished in time are nacked, and their consuming contexts are canceled.
+// Their consumers are not waited for, so they may still be running after Start returns and the Subscriber is closed.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		if timeout > 0 {
+			c.ShutdownTimeout = timeout
+		}
+	}
+}
+
+// WithConsumeTimeout returns an Option that sets the timeout of the context passed to Consumer and BatchConsumer.
+func WithConsumeTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		if timeout > 0 {
+			c.ConsumeTimeout = timeout
+		}
+	}
+}
+
+// WithLabel returns an Option that sets the label to identify the engine in logs and stats.
+// The label is available with GetLabel in consuming contexts and with BeginTag in StatsHandler.
+func WithLabel(label string) Option {
+	return func(c *Config) {
+		c.Label = label
+	}
+}
+
+// WithOrderingKey returns an Option that consumes messages with the same ordering key sequentially,
+// while messages with different keys are consumed in parallel.
+// It is applied to Consumer, and ignored with BatchConsumer.
+// Brokers must deliver messages with the same key in order, e.g. Cloud Pub/Sub subscriptions with message ordering enabled.
+func WithOrderingKey(f OrderingKeyFunc) Option {
+	return func(c *Config) {
+		c.OrderingKeyFunc = f
+	}
+}
+
+// WithOrderingPolicy returns an Option that sets how the messages following a nacked message with the same ordering key are treated.
+// NackFollowing is used by default.
+func WithOrderingPolicy(policy OrderingPolicy) Option {
+	return func(c *Config) {
+		c.OrderingPolicy = policy
+	}
+}
+
+// withSemaphore returns an Option that sets the consumption slots shared with other engines.
+func withSemaphore(sem chan struct{}) Option {
+	return func(c *Config) {
+		c.sem = sem
+	}
+}
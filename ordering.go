@@ -0,0 +1,94 @@
+package subee
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPrecedingNacked is the error that a preceding message with the same ordering key was nacked.
+var ErrPrecedingNacked = errors.New("a preceding message with the same ordering key was nacked")
+
+// OrderingKeyFunc returns the ordering key of the message.
+// Messages with the same key are consumed sequentially, and messages with an empty key are consumed without ordering.
+type OrderingKeyFunc func(Message) string
+
+// OrderingKeyFromMessage is an OrderingKeyFunc that returns the ordering key of messages implementing ExtendedMessage.
+func OrderingKeyFromMessage(msg Message) string {
+	if em, ok := msg.(ExtendedMessage); ok {
+		return em.OrderingKey()
+	}
+	return ""
+}
+
+// OrderingKeyFromMetadata returns an OrderingKeyFunc that returns the metadata value for the key.
+func OrderingKeyFromMetadata(key string) OrderingKeyFunc {
+	return func(msg Message) string {
+		return msg.Metadata()[key]
+	}
+}
+
+// OrderingPolicy represents how the messages following a nacked message with the same ordering key are treated.
+type OrderingPolicy int
+
+const (
+	// NackFollowing nacks the following messages received so far without consuming them with ErrPrecedingNacked.
+	// Brokers that redeliver nacked messages in order, like Cloud Pub/Sub, then redeliver them after the nacked one.
+	NackFollowing OrderingPolicy = iota
+	// ContinueFollowing consumes the following messages regardless of the nacked one.
+	ContinueFollowing
+)
+
+// orderedQueues runs the consumptions of messages with the same ordering key sequentially.
+// A goroutine is started for a key while its queue has messages.
+type orderedQueues struct {
+	mu     sync.Mutex
+	queues map[string]*orderedQueue
+}
+
+// orderedTask consumes a message and reports whether it was nacked.
+// precedingNacked is true if a preceding message in the queue was nacked.
+type orderedTask func(precedingNacked bool) (nacked bool)
+
+type orderedQueue struct {
+	tasks []orderedTask
+}
+
+func newOrderedQueues() *orderedQueues {
+	return &orderedQueues{queues: make(map[string]*orderedQueue)}
+}
+
+func (q *orderedQueues) push(key string, task orderedTask) {
+	q.mu.Lock()
+	oq, ok := q.queues[key]
+	if !ok {
+		oq = new(orderedQueue)
+		q.queues[key] = oq
+	}
+	oq.tasks = append(oq.tasks, task)
+	q.mu.Unlock()
+
+	if !ok {
+		go q.run(key, oq)
+	}
+}
+
+// run consumes the queued messages in order until the queue is empty.
+func (q *orderedQueues) run(key string, oq *orderedQueue) {
+	var nacked bool
+	for {
+		q.mu.Lock()
+		if len(oq.tasks) == 0 {
+			delete(q.queues, key)
+			q.mu.Unlock()
+			return
+		}
+		task := oq.tasks[0]
+		oq.tasks = oq.tasks[1:]
+		q.mu.Unlock()
+
+		if task(nacked) {
+			nacked = true
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package subee_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wantedly/subee"
+	subee_testing "github.com/wantedly/subee/testing"
+)
+
+func TestEngineWithOrderingKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var (
+		mu       sync.Mutex
+		got      = make(map[string][]string)
+		running  = make(map[string]bool)
+		overlap  bool
+		bStarted = make(chan struct{})
+	)
+
+	subscriber := subee_testing.NewFakeSubscriber()
+	consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		key := subee.OrderingKeyFromMessage(msg)
+
+		mu.Lock()
+		if running[key] {
+			overlap = true
+		}
+		running[key] = true
+		got[key] = append(got[key], string(msg.Data()))
+		mu.Unlock()
+
+		switch string(msg.Data()) {
+		case "a1":
+			// Messages with other keys are consumed in parallel.
+			select {
+			case <-bStarted:
+			case <-time.After(time.Second):
+				t.Error("b1 was not consumed while a1 was being consumed")
+			}
+		case "b1":
+			close(bStarted)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running[key] = false
+		mu.Unlock()
+		return nil
+	})
+
+	engine := subee.New(
+		subscriber,
+		consumer,
+		subee.WithOrderingKey(subee.OrderingKeyFromMessage),
+		subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+	)
+
+	msgs := []*subee_testing.FakeExtendedMessage{
+		subee_testing.NewFakeExtendedMessage([]byte("a1"), "1", time.Time{}, 1, "a"),
+		subee_testing.NewFakeExtendedMessage([]byte("a2"), "2", time.Time{}, 1, "a"),
+		subee_testing.NewFakeExtendedMessage([]byte("b1"), "3", time.Time{}, 1, "b"),
+		subee_testing.NewFakeExtendedMessage([]byte("a3"), "4", time.Time{}, 1, "a"),
+		subee_testing.NewFakeExtendedMessage([]byte("b2"), "5", time.Time{}, 1, "b"),
+	}
+	go func() {
+		for _, m := range msgs {
+			subscriber.AddMessage(m)
+		}
+		cancel()
+	}()
+
+	if err := engine.Start(ctx); err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	want := map[string][]string{
+		"a": {"a1", "a2", "a3"},
+		"b": {"b1", "b2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("consumed messages are %v, want %v", got, want)
+	}
+	if overlap {
+		t.Error("messages with the same ordering key were consumed concurrently")
+	}
+	for i, m := range msgs {
+		if !m.Acked() {
+			t.Errorf("Messages[%d].Acked() is false, want true", i)
+		}
+	}
+}
+
+func TestEngineWithOrderingPolicy(t *testing.T) {
+	cases := []struct {
+		policy   subee.OrderingPolicy
+		consumed []string
+		acked    []bool
+	}{
+		{
+			policy:   subee.NackFollowing,
+			consumed: []string{"1"},
+			acked:    []bool{false, false, false},
+		},
+		{
+			policy:   subee.ContinueFollowing,
+			consumed: []string{"1", "2", "3"},
+			acked:    []bool{false, true, true},
+		},
+	}
+
+	for _, c := range cases {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var (
+			mu       sync.Mutex
+			consumed []string
+			release  = make(chan struct{})
+		)
+
+		subscriber := subee_testing.NewFakeSubscriber()
+		consumer := subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+			if string(msg.Data()) == "sync" {
+				return nil
+			}
+
+			mu.Lock()
+			consumed = append(consumed, string(msg.Data()))
+			mu.Unlock()
+
+			if string(msg.Data()) == "1" {
+				<-release
+				return errors.New("error")
+			}
+			return nil
+		})
+
+		engine := subee.New(
+			subscriber,
+			consumer,
+			subee.WithOrderingKey(subee.OrderingKeyFromMetadata("key")),
+			subee.WithOrderingPolicy(c.policy),
+			subee.WithLogger(log.New(ioutil.Discard, "", 0)),
+		)
+
+		msgs := []*subee_testing.FakeMessage{
+			subee_testing.NewFakeMessageWithMetadata([]byte("1"), map[string]string{"key": "a"}),
+			subee_testing.NewFakeMessageWithMetadata([]byte("2"), map[string]string{"key": "a"}),
+			subee_testing.NewFakeMessageWithMetadata([]byte("3"), map[string]string{"key": "a"}),
+		}
+		go func() {
+			for _, m := range msgs {
+				subscriber.AddMessage(m)
+			}
+			// AddMessage returns before the message is handed over, so another message is added
+			// to ensure the following messages are queued while the first one is being consumed.
+			subscriber.AddMessage(subee_testing.NewFakeMessage([]byte("sync"), false, false))
+			close(release)
+			cancel()
+		}()
+
+		if err := engine.Start(ctx); err != nil {
+			t.Errorf("Start returned an error: %v", err)
+		}
+
+		if !reflect.DeepEqual(consumed, c.consumed) {
+			t.Errorf("consumed messages with policy %d are %v, want %v", c.policy, consumed, c.consumed)
+		}
+		for i, want := range c.acked {
+			if got := msgs[i].Acked(); got != want {
+				t.Errorf("Messages[%d].Acked() with policy %d is %t, want %t", i, c.policy, got, want)
+			}
+			if got := msgs[i].Nacked(); got != !want {
+				t.Errorf("Messages[%d].Nacked() with policy %d is %t, want %t", i, c.policy, got, !want)
+			}
+		}
+	}
+}
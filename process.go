@@ -2,7 +2,9 @@ package subee
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -11,41 +13,169 @@ import (
 // process encapsulates a subscribing routine and a consuming messages routine.
 type process interface {
 	Start(ctx context.Context) error
+	Stop(ctx context.Context) *ShutdownReport
 }
 
 type processImpl struct {
 	*Engine
-	wg sync.WaitGroup
+	wg       sync.WaitGroup
+	sems     []chan struct{}
+	inFlight int32
+
+	acked, nacked, abandoned int32
+
+	mu      sync.Mutex
+	msgs    map[*trackedMessage]struct{}
+	stopCtx context.Context
+
+	ordered *orderedQueues
+
+	// baseCtx is the parent of consuming contexts.
+	// It carries values of the context passed to Start, but is not canceled with it.
+	baseCtx context.Context
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	abortCh  chan struct{}
+	doneCh   chan struct{}
+	report   *ShutdownReport
 }
 
 func newProcess(e *Engine) process {
-	return &processImpl{
-		Engine: e,
+	p := &processImpl{
+		Engine:  e,
+		msgs:    make(map[*trackedMessage]struct{}),
+		ordered: newOrderedQueues(),
+		stopCh:  make(chan struct{}),
+		abortCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	// The slot of the engine is acquired before the one shared in the group,
+	// so that the engine waiting for its own slot does not hold the shared one.
+	if e.MaxConcurrency > 0 {
+		p.sems = append(p.sems, make(chan struct{}, e.MaxConcurrency))
 	}
+	if e.sem != nil {
+		p.sems = append(p.sems, e.sem)
+	}
+	return p
 }
 
 func (p *processImpl) Start(ctx context.Context) error {
 	p.Logger.Print("Start process")
 	defer p.Logger.Print("Finish process")
-	defer p.wg.Wait() // To wait for consuming all received messages.
+	defer close(p.doneCh)
 
+	var start func(context.Context) error
 	switch {
 	case p.Consumer != nil:
-		return errors.WithStack(p.startConsumingProcess(ctx))
+		start = p.startConsumingProcess
 	case p.BatchConsumer != nil:
-		return errors.WithStack(p.startBatchConsumingProcess(ctx))
+		start = p.startBatchConsumingProcess
 	default:
 		panic("unreachable")
 	}
+
+	p.baseCtx = context.WithoutCancel(ctx)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	subscribed := make(chan struct{})
+	go func() {
+		defer close(subscribed)
+		err = start(ctx)
+	}()
+
+	select {
+	case <-subscribed:
+	case <-ctx.Done():
+	case <-p.stopCh:
+	}
+	cancel()
+
+	p.report = p.drain(subscribed)
+
+	return errors.WithStack(err)
+}
+
+// Stop stops subscribing and waits for the process to be drained.
+func (p *processImpl) Stop(ctx context.Context) *ShutdownReport {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		p.stopCtx = ctx
+		p.mu.Unlock()
+		close(p.stopCh)
+	})
+	<-p.doneCh
+	return p.report
+}
+
+// drain waits for consuming all received messages.
+// Messages still being consumed when the shutdown deadline is exceeded are nacked.
+func (p *processImpl) drain(subscribed <-chan struct{}) *ShutdownReport {
+	p.Logger.Print("Start draining messages")
+	defer p.Logger.Print("Finish draining messages")
+
+	acked, nacked := atomic.LoadInt32(&p.acked), atomic.LoadInt32(&p.nacked)
+
+	ctx, cancel := p.createDrainingContext()
+	defer cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-subscribed
+		p.wg.Wait()
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		close(p.abortCh)
+		<-subscribed
+		p.abandonAll()
+	}
+
+	report := &ShutdownReport{
+		Acked:     int(atomic.LoadInt32(&p.acked) - acked),
+		Nacked:    int(atomic.LoadInt32(&p.nacked) - nacked),
+		Abandoned: int(atomic.LoadInt32(&p.abandoned)),
+	}
+	p.Logger.Printf("Drained messages: %d acked, %d nacked, %d abandoned", report.Acked, report.Nacked, report.Abandoned)
+
+	return report
+}
+
+func (p *processImpl) createDrainingContext() (context.Context, context.CancelFunc) {
+	p.mu.Lock()
+	ctx := p.stopCtx
+	p.mu.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if p.ShutdownTimeout > 0 {
+		return context.WithTimeout(ctx, p.ShutdownTimeout)
+	}
+	return context.WithCancel(ctx)
 }
 
 func (p *processImpl) startConsumingProcess(ctx context.Context) error {
 	consumer := chainConsumerInterceptors(p.Consumer, p.ConsumerInterceptors...)
 
 	err := p.subscribe(ctx, func(in Message) {
-		p.handleMessage(p.createConsumingContext(), &singleMessage{Message: in}, func(ctx context.Context) error {
+		handle := func(ctx context.Context) error {
 			return errors.WithStack(consumer.Consume(ctx, in))
-		})
+		}
+		if p.OrderingKeyFunc != nil {
+			if key := p.OrderingKeyFunc(in); key != "" {
+				p.handleOrderedMessage(p.createConsumingContext(), key, &singleMessage{Message: in}, handle)
+				return
+			}
+		}
+		p.handleMessage(p.createConsumingContext(), &singleMessage{Message: in}, handle)
 	})
 	return errors.WithStack(err)
 }
@@ -57,11 +187,13 @@ func (p *processImpl) startBatchConsumingProcess(ctx context.Context) error {
 		p.FlushInterval,
 	)
 
+	// To hand over all buffered messages before finishing.
+	consumingDone := make(chan struct{})
+	defer func() { <-consumingDone }()
 	defer close(inCh)
 
-	p.wg.Add(1)
 	go func() {
-		defer p.wg.Done()
+		defer close(consumingDone)
 		batchConsumer := chainBatchConsumerInterceptors(p.BatchConsumer, p.BatchConsumerInterceptors...)
 
 		p.Logger.Print("Start batch consuming process")
@@ -85,55 +217,247 @@ func (p *processImpl) subscribe(ctx context.Context, f func(msg Message)) error
 }
 
 func (p *processImpl) createConsumingContext() context.Context {
-	ctx := context.Background()
-	ctx = p.StatsHandler.TagProcess(ctx, &BeginTag{})
+	ctx := p.baseCtx
+	ctx = p.StatsHandler.TagProcess(ctx, &BeginTag{Label: p.Label})
 	ctx = p.StatsHandler.TagProcess(ctx, &EnqueueTag{})
 	ctx = setEnqueuedAt(ctx, time.Now().UTC())
 	return ctx
 }
 
+// acquire blocks until a consumption slot is available.
+// It blocks the caller, so the subscriber is backpressured when MaxConcurrency is reached.
+// It returns false when the process has been aborted.
+func (p *processImpl) acquire(ctx context.Context) bool {
+	beginTime := time.Now()
+
+	select {
+	case <-p.abortCh:
+		return false
+	default:
+	}
+
+	for i, sem := range p.sems {
+		select {
+		case sem <- struct{}{}:
+		case <-p.abortCh:
+			for _, sem := range p.sems[:i] {
+				<-sem
+			}
+			return false
+		}
+	}
+	inFlight := atomic.AddInt32(&p.inFlight, 1)
+
+	p.StatsHandler.HandleProcess(ctx, &ConcurrencyWait{
+		BeginTime: beginTime,
+		EndTime:   time.Now(),
+		InFlight:  int(inFlight),
+	})
+
+	return true
+}
+
+func (p *processImpl) release() {
+	atomic.AddInt32(&p.inFlight, -1)
+
+	for _, sem := range p.sems {
+		<-sem
+	}
+}
+
+func (p *processImpl) track(m queuedMessage, cancel context.CancelFunc) *trackedMessage {
+	t := &trackedMessage{queuedMessage: m, cancel: cancel}
+	p.mu.Lock()
+	p.msgs[t] = struct{}{}
+	p.mu.Unlock()
+	return t
+}
+
+func (p *processImpl) untrack(t *trackedMessage) {
+	p.mu.Lock()
+	delete(p.msgs, t)
+	p.mu.Unlock()
+}
+
+func (p *processImpl) settle(ctx context.Context, t *trackedMessage, err error) (s settlement) {
+	t.settleOnce(func() {
+		s = t.settle(err)
+		atomic.AddInt32(&p.acked, int32(s.acked))
+		atomic.AddInt32(&p.nacked, int32(s.nacked))
+	})
+	s.ackErrors = p.waitAckResults(ctx, s.results)
+	return
+}
+
+// waitAckResults waits for the results of acknowledgements, and reports the failed ones.
+// It returns the number of the failed acknowledgements.
+func (p *processImpl) waitAckResults(ctx context.Context, results []pendingAckResult) (failed int) {
+	for _, r := range results {
+		err := r.result.Get(ctx)
+		if err == nil {
+			continue
+		}
+		failed++
+
+		op := "ack"
+		if !r.ack {
+			op = "nack"
+		}
+		p.Logger.Printf("Failed to %s %s: %v", op, describeMessage(r.msg), err)
+		p.StatsHandler.HandleProcess(ctx, &AckError{
+			Ack:   r.ack,
+			Error: err,
+		})
+	}
+	return
+}
+
+func (p *processImpl) abandon(t *trackedMessage) {
+	t.cancel()
+	t.settleOnce(func() {
+		t.Nack()
+		atomic.AddInt32(&p.abandoned, int32(t.Count()))
+	})
+}
+
+func (p *processImpl) abandonAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for t := range p.msgs {
+		p.abandon(t)
+	}
+}
+
 func (p *processImpl) handleMessage(ctx context.Context, m queuedMessage, handle func(context.Context) error) {
+	ctx, cancel := context.WithCancel(ctx)
+	t := p.track(m, cancel)
+
+	if !p.acquire(ctx) {
+		p.abandon(t)
+		p.untrack(t)
+		return
+	}
+
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
-		p.Logger.Printf("Start consuming %d messages", m.Count())
-		defer p.Logger.Printf("Finish consuming %d messages", m.Count())
+		p.consume(ctx, t, handle)
+	}()
+}
+
+// handleOrderedMessage consumes the message after the preceding messages with the same ordering key.
+// The consumption slot is acquired on receipt, so messages waiting for their turn count towards MaxConcurrency.
+func (p *processImpl) handleOrderedMessage(ctx context.Context, key string, m queuedMessage, handle func(context.Context) error) {
+	ctx, cancel := context.WithCancel(ctx)
+	t := p.track(m, cancel)
 
-		if p.AckImmediately {
-			m.Ack()
+	if !p.acquire(ctx) {
+		p.abandon(t)
+		p.untrack(t)
+		return
+	}
+
+	p.wg.Add(1)
+	p.ordered.push(key, func(precedingNacked bool) (nacked bool) {
+		defer p.wg.Done()
+
+		// The message has been abandoned while waiting.
+		if ctx.Err() != nil {
+			p.release()
+			p.untrack(t)
+			return false
 		}
 
-		enqueuedAt := getEnqueuedAt(ctx)
+		if precedingNacked && p.OrderingPolicy == NackFollowing {
+			handle = func(context.Context) error { return errors.WithStack(ErrPrecedingNacked) }
+		}
 
-		p.StatsHandler.HandleProcess(ctx, &Dequeue{
-			BeginTime: enqueuedAt,
-			EndTime:   time.Now(),
-		})
+		return p.consume(ctx, t, handle).nacked > 0
+	})
+}
 
-		beginTime := time.Now()
+// consume runs handle, settles the message by its result and releases the consumption slot.
+func (p *processImpl) consume(ctx context.Context, t *trackedMessage, handle func(context.Context) error) (s settlement) {
+	defer p.release()
+	defer p.untrack(t)
+	defer t.cancel()
 
-		ctx = p.StatsHandler.TagProcess(ctx, &ConsumeBeginTag{})
+	m := t.queuedMessage
+	desc := describe(m)
+	p.Logger.Printf("Start consuming %s", desc)
+	defer p.Logger.Printf("Finish consuming %s", desc)
 
-		err := handle(ctx)
+	if p.AckImmediately {
+		s = p.settle(ctx, t, nil)
+	}
 
-		if !p.AckImmediately {
-			if err != nil {
-				m.Nack()
-			} else {
-				m.Ack()
-			}
-		}
+	enqueuedAt := getEnqueuedAt(ctx)
 
-		p.StatsHandler.HandleProcess(ctx, &ConsumeEnd{
-			BeginTime: beginTime,
-			EndTime:   time.Now(),
-			Error:     err,
-		})
+	p.StatsHandler.HandleProcess(ctx, &Dequeue{
+		BeginTime:   enqueuedAt,
+		EndTime:     time.Now(),
+		PublishTime: earliestPublishTime(m.messages()),
+	})
 
-		p.StatsHandler.HandleProcess(ctx, &End{
-			MsgCount:  m.Count(),
-			BeginTime: enqueuedAt,
-			EndTime:   time.Now(),
-		})
-	}()
+	beginTime := time.Now()
+
+	ctx = p.StatsHandler.TagProcess(ctx, &ConsumeBeginTag{})
+
+	consumingCtx := ctx
+	if p.ConsumeTimeout > 0 {
+		var cancel context.CancelFunc
+		consumingCtx, cancel = context.WithTimeout(ctx, p.ConsumeTimeout)
+		defer cancel()
+	}
+
+	err := handle(consumingCtx)
+
+	if !p.AckImmediately {
+		s = p.settle(ctx, t, err)
+	}
+
+	if IsSkip(err) {
+		err = nil
+	}
+
+	p.StatsHandler.HandleProcess(ctx, &ConsumeEnd{
+		BeginTime: beginTime,
+		EndTime:   time.Now(),
+		Error:     err,
+	})
+
+	p.StatsHandler.HandleProcess(ctx, &End{
+		MsgCount:      m.Count(),
+		AckCount:      s.acked,
+		NackCount:     s.nacked,
+		DropCount:     s.dropped,
+		AckErrorCount: s.ackErrors,
+		BeginTime:     enqueuedAt,
+		EndTime:       time.Now(),
+	})
+
+	return s
+}
+
+// describe returns the description of the messages for logging.
+// A single message is described with its ID and delivery attempt if it implements ExtendedMessage.
+func describe(m queuedMessage) string {
+	msgs := m.messages()
+	if len(msgs) == 1 {
+		if em, ok := msgs[0].(ExtendedMessage); ok && em.MessageID() != "" {
+			return describeMessage(em)
+		}
+	}
+	return fmt.Sprintf("%d messages", len(msgs))
+}
+
+func describeMessage(msg Message) string {
+	em, ok := msg.(ExtendedMessage)
+	if !ok || em.MessageID() == "" {
+		return "a message"
+	}
+	if n := em.DeliveryAttempt(); n > 0 {
+		return fmt.Sprintf("message %s (delivery attempt %d)", em.MessageID(), n)
+	}
+	return fmt.Sprintf("message %s", em.MessageID())
 }
@@ -2,12 +2,67 @@ package subee
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type queuedMessage interface {
 	Acknowledger
 	Count() int
+	messages() []Message
+	// settle acks or nacks messages according to the consumption error.
+	settle(err error) settlement
+}
+
+// settlement represents the number of messages settled by their consumption error.
+type settlement struct {
+	acked, nacked, dropped int
+	// results are the pending results of acknowledgements of messages implementing ResultAcknowledger.
+	results []pendingAckResult
+	// ackErrors is the number of the failed acknowledgements in results.
+	ackErrors int
+}
+
+type pendingAckResult struct {
+	msg    Message
+	ack    bool
+	result AckResult
+}
+
+func (s *settlement) add(o settlement) {
+	s.acked += o.acked
+	s.nacked += o.nacked
+	s.dropped += o.dropped
+	s.results = append(s.results, o.results...)
+}
+
+func settleMessage(msg Message, err error) settlement {
+	switch classifyError(err) {
+	case noErrorKind, skipErrorKind:
+		return settlement{acked: 1, results: ack(msg)}
+	case permanentErrorKind:
+		return settlement{acked: 1, dropped: 1, results: ack(msg)}
+	default:
+		return settlement{nacked: 1, results: nack(msg)}
+	}
+}
+
+func ack(msg Message) []pendingAckResult {
+	if ra, ok := msg.(ResultAcknowledger); ok {
+		return []pendingAckResult{{msg: msg, ack: true, result: ra.AckWithResult()}}
+	}
+	msg.Ack()
+	return nil
+}
+
+func nack(msg Message) []pendingAckResult {
+	if ra, ok := msg.(ResultAcknowledger); ok {
+		return []pendingAckResult{{msg: msg, ack: false, result: ra.NackWithResult()}}
+	}
+	msg.Nack()
+	return nil
 }
 
 type singleMessage struct {
@@ -16,6 +71,12 @@ type singleMessage struct {
 
 func (s *singleMessage) Count() int { return 1 }
 
+func (s *singleMessage) messages() []Message { return []Message{s.Message} }
+
+func (s *singleMessage) settle(err error) settlement {
+	return settleMessage(s.Message, err)
+}
+
 type multiMessages struct {
 	Ctx  context.Context
 	Msgs []Message
@@ -35,6 +96,35 @@ func (m *multiMessages) Nack() {
 
 func (m *multiMessages) Count() int { return len(m.Msgs) }
 
+func (m *multiMessages) messages() []Message { return m.Msgs }
+
+func (m *multiMessages) settle(err error) (s settlement) {
+	var batchErr BatchError
+	partial := errors.As(err, &batchErr)
+
+	for i, msg := range m.Msgs {
+		msgErr := err
+		if partial {
+			msgErr = batchErr[i]
+		}
+		s.add(settleMessage(msg, msgErr))
+	}
+	return s
+}
+
+// trackedMessage guards a queuedMessage from being acked or nacked more than once.
+type trackedMessage struct {
+	queuedMessage
+	settled int32
+	cancel  context.CancelFunc
+}
+
+func (t *trackedMessage) settleOnce(f func()) {
+	if atomic.CompareAndSwapInt32(&t.settled, 0, 1) {
+		f()
+	}
+}
+
 func createBufferedQueue(
 	createCtx func() context.Context,
 	chunkSize int,
@@ -2,14 +2,26 @@ package subee
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 type fakeMessage struct {
 	Message
 }
 
+type ackMessage struct {
+	Message
+	acked, nacked bool
+}
+
+func (m *ackMessage) Ack()  { m.acked = true }
+func (m *ackMessage) Nack() { m.nacked = true }
+
 func queuing(inCh chan<- Message) {
 	go func() {
 		inCh <- &fakeMessage{}
@@ -49,3 +61,36 @@ func TestCreateBufferedQueue(t *testing.T) {
 		t.Error("out channel should close")
 	}
 }
+
+func TestMultiMessages_settle(t *testing.T) {
+	wraps := map[string]func(error) error{
+		"WithStack": func(err error) error { return errors.WithStack(err) },
+		"Errorf":    func(err error) error { return fmt.Errorf("failed to consume: %w", err) },
+	}
+
+	for name, wrap := range wraps {
+		msgs := make([]Message, 4)
+		for i := range msgs {
+			msgs[i] = &ackMessage{}
+		}
+		m := &multiMessages{Msgs: msgs}
+
+		err := wrap(BatchError{
+			1: errors.New("error"),
+			2: Permanent(errors.New("error")),
+			3: Skip,
+		})
+
+		if got, want := m.settle(err), (settlement{acked: 3, nacked: 1, dropped: 1}); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: settle() returned %+v, want %+v", name, got, want)
+		}
+		for i, want := range []bool{true, false, true, true} {
+			if got := msgs[i].(*ackMessage).acked; got != want {
+				t.Errorf("%s: Messages[%d] acked is %t, want %t", name, i, got, want)
+			}
+			if got := msgs[i].(*ackMessage).nacked; got != !want {
+				t.Errorf("%s: Messages[%d] nacked is %t, want %t", name, i, got, !want)
+			}
+		}
+	}
+}
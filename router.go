@@ -0,0 +1,183 @@
+package subee
+
+import (
+	"context"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnmatched is the error that no handlers of Router match the message.
+var ErrUnmatched = errors.New("no handlers matched the message")
+
+// UnmatchedPolicy represents how Router treats messages that no handlers match.
+type UnmatchedPolicy int
+
+const (
+	// NackUnmatched nacks unmatched messages so that they are redelivered.
+	NackUnmatched UnmatchedPolicy = iota
+	// AckUnmatched acks unmatched messages without regarding them as failed.
+	AckUnmatched
+	// DropUnmatched acks unmatched messages and reports them as dropped.
+	DropUnmatched
+)
+
+// RouterOption configures Router.
+type RouterOption func(*Router)
+
+// WithUnmatchedPolicy returns a RouterOption that sets how unmatched messages are treated.
+// It is ignored when the fallback handler is registered.
+func WithUnmatchedPolicy(policy UnmatchedPolicy) RouterOption {
+	return func(r *Router) {
+		r.policy = policy
+	}
+}
+
+// Router is a Consumer and BatchConsumer that dispatches messages to the handlers matched with them.
+// Handlers are matched in order of registration.
+type Router struct {
+	routes   []*route
+	fallback Consumer
+	policy   UnmatchedPolicy
+}
+
+type route struct {
+	match    func(Message) bool
+	consumer Consumer
+}
+
+// NewRouter creates a new Router instance.
+func NewRouter(opts ...RouterOption) *Router {
+	r := new(Router)
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// Handle registers the consumer for messages whose metadata has the value for the key.
+func (r *Router) Handle(key, value string, consumer Consumer) {
+	r.HandlePredicate(func(msg Message) bool {
+		v, ok := msg.Metadata()[key]
+		return ok && v == value
+	}, consumer)
+}
+
+// HandlePattern registers the consumer for messages whose metadata value for the key matches the pattern.
+// The pattern syntax is the same as path.Match, and HandlePattern panics if the pattern is malformed.
+func (r *Router) HandlePattern(key, pattern string, consumer Consumer) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic(errors.Wrapf(err, "invalid pattern %q", pattern))
+	}
+	r.HandlePredicate(func(msg Message) bool {
+		v, ok := msg.Metadata()[key]
+		if !ok {
+			return false
+		}
+		matched, _ := path.Match(pattern, v)
+		return matched
+	}, consumer)
+}
+
+// HandlePredicate registers the consumer for messages that the predicate reports true.
+func (r *Router) HandlePredicate(pred func(Message) bool, consumer Consumer) {
+	r.routes = append(r.routes, &route{match: pred, consumer: consumer})
+}
+
+// Fallback registers the consumer for messages that no handlers match.
+func (r *Router) Fallback(consumer Consumer) {
+	r.fallback = consumer
+}
+
+// Consume implements Consumer.Consume.
+func (r *Router) Consume(ctx context.Context, msg Message) error {
+	return errors.WithStack(r.consumer(r.lookup(msg)).Consume(ctx, msg))
+}
+
+// BatchConsume implements BatchConsumer.BatchConsume.
+// Messages are grouped by the matched handler, and handlers implementing BatchConsumer consume their group at once.
+func (r *Router) BatchConsume(ctx context.Context, msgs []Message) error {
+	var (
+		routes []int
+		groups = make(map[int][]int)
+	)
+	for i, msg := range msgs {
+		rt := r.lookup(msg)
+		if _, ok := groups[rt]; !ok {
+			routes = append(routes, rt)
+		}
+		groups[rt] = append(groups[rt], i)
+	}
+
+	failed := make(BatchError)
+	for _, rt := range routes {
+		c, idxs := r.consumer(rt), groups[rt]
+
+		if bc, ok := c.(BatchConsumer); ok {
+			group := make([]Message, len(idxs))
+			for i, idx := range idxs {
+				group[i] = msgs[idx]
+			}
+
+			err := bc.BatchConsume(ctx, group)
+			if err == nil {
+				continue
+			}
+			var batchErr BatchError
+			partial := errors.As(err, &batchErr)
+			for i, idx := range idxs {
+				if !partial {
+					failed[idx] = err
+				} else if batchErr[i] != nil {
+					failed[idx] = batchErr[i]
+				}
+			}
+			continue
+		}
+
+		for _, idx := range idxs {
+			if err := c.Consume(ctx, msgs[idx]); err != nil {
+				failed[idx] = err
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.WithStack(failed)
+}
+
+// lookup returns the index of the route matched with the message, or -1 if no routes match.
+func (r *Router) lookup(msg Message) int {
+	for i, rt := range r.routes {
+		if rt.match(msg) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Router) consumer(rt int) Consumer {
+	switch {
+	case rt >= 0:
+		return r.routes[rt].consumer
+	case r.fallback != nil:
+		return r.fallback
+	default:
+		return unmatchedConsumer(r.policy)
+	}
+}
+
+type unmatchedConsumer UnmatchedPolicy
+
+func (c unmatchedConsumer) Consume(context.Context, Message) error {
+	switch UnmatchedPolicy(c) {
+	case AckUnmatched:
+		return Skip
+	case DropUnmatched:
+		return Permanent(ErrUnmatched)
+	default:
+		return ErrUnmatched
+	}
+}
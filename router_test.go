@@ -0,0 +1,164 @@
+package subee_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+	subee_testing "github.com/wantedly/subee/testing"
+)
+
+type recordingConsumer struct {
+	mu      sync.Mutex
+	name    string
+	records *[]string
+	err     error
+}
+
+func (c *recordingConsumer) Consume(ctx context.Context, msg subee.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.records = append(*c.records, c.name+":"+string(msg.Data()))
+	return c.err
+}
+
+type recordingBatchConsumer struct {
+	*recordingConsumer
+	batches [][]subee.Message
+}
+
+func (c *recordingBatchConsumer) BatchConsume(ctx context.Context, msgs []subee.Message) error {
+	c.batches = append(c.batches, msgs)
+	for _, msg := range msgs {
+		c.recordingConsumer.Consume(ctx, msg)
+	}
+	return subee.BatchError{0: errors.New("error")}
+}
+
+func newEventMessage(data, eventType string) *subee_testing.FakeMessage {
+	return subee_testing.NewFakeMessageWithMetadata([]byte(data), map[string]string{"event_type": eventType})
+}
+
+func TestRouter_Consume(t *testing.T) {
+	var records []string
+
+	router := subee.NewRouter()
+	router.Handle("event_type", "book.created", &recordingConsumer{name: "created", records: &records})
+	router.HandlePattern("event_type", "book.*", &recordingConsumer{name: "book", records: &records})
+	router.HandlePredicate(func(msg subee.Message) bool {
+		return string(msg.Data()) == "author"
+	}, &recordingConsumer{name: "author", records: &records})
+
+	tests := []struct {
+		msg  subee.Message
+		want string
+		err  func(error) bool
+	}{
+		{msg: newEventMessage("foo", "book.created"), want: "created:foo"},
+		{msg: newEventMessage("bar", "book.deleted"), want: "book:bar"},
+		{msg: newEventMessage("author", "author.created"), want: "author:author"},
+		{msg: newEventMessage("baz", "author.created"), err: subee.IsRetryable},
+	}
+
+	for _, test := range tests {
+		records = nil
+		err := router.Consume(context.Background(), test.msg)
+
+		if test.err != nil {
+			if !test.err(err) {
+				t.Errorf("Consume(%q) returned unexpected error: %v", test.msg.Data(), err)
+			}
+			if got, want := errors.Cause(err), subee.ErrUnmatched; got != want {
+				t.Errorf("Consume(%q) returned %v, want %v", test.msg.Data(), got, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Consume(%q) returned %v, want nil", test.msg.Data(), err)
+		}
+		if got, want := records, []string{test.want}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Consume(%q) dispatched to %v, want %v", test.msg.Data(), got, want)
+		}
+	}
+}
+
+func TestRouter_Unmatched(t *testing.T) {
+	var records []string
+
+	tests := []struct {
+		router func() *subee.Router
+		err    func(error) bool
+	}{
+		{
+			router: func() *subee.Router { return subee.NewRouter(subee.WithUnmatchedPolicy(subee.AckUnmatched)) },
+			err:    subee.IsSkip,
+		},
+		{
+			router: func() *subee.Router { return subee.NewRouter(subee.WithUnmatchedPolicy(subee.DropUnmatched)) },
+			err:    subee.IsPermanent,
+		},
+		{
+			router: func() *subee.Router {
+				r := subee.NewRouter(subee.WithUnmatchedPolicy(subee.DropUnmatched))
+				r.Fallback(&recordingConsumer{name: "fallback", records: &records})
+				return r
+			},
+			err: func(err error) bool { return err == nil },
+		},
+	}
+
+	for i, test := range tests {
+		err := test.router().Consume(context.Background(), newEventMessage("foo", "book.created"))
+		if !test.err(err) {
+			t.Errorf("Consume() of router[%d] returned unexpected error: %v", i, err)
+		}
+	}
+
+	if got, want := records, []string{"fallback:foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fallback consumed %v, want %v", got, want)
+	}
+}
+
+func TestRouter_BatchConsume(t *testing.T) {
+	var records []string
+
+	batch := &recordingBatchConsumer{recordingConsumer: &recordingConsumer{name: "batch", records: &records}}
+
+	router := subee.NewRouter()
+	router.Handle("event_type", "book.created", batch)
+	router.Handle("event_type", "book.deleted", &recordingConsumer{name: "deleted", records: &records, err: errors.New("error")})
+	router.Fallback(&recordingConsumer{name: "fallback", records: &records})
+
+	msgs := []subee.Message{
+		newEventMessage("foo", "book.deleted"),
+		newEventMessage("bar", "book.created"),
+		newEventMessage("baz", "author.created"),
+		newEventMessage("qux", "book.created"),
+	}
+
+	err := router.BatchConsume(context.Background(), msgs)
+
+	if want := []string{"deleted:foo", "batch:bar", "batch:qux", "fallback:baz"}; !reflect.DeepEqual(records, want) {
+		t.Errorf("BatchConsume() dispatched to %v, want %v", records, want)
+	}
+	if got, want := len(batch.batches), 1; got != want {
+		t.Errorf("BatchConsume() of the handler called %d times, want %d", got, want)
+	}
+
+	var batchErr subee.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchConsume() returned %v, want subee.BatchError", err)
+	}
+	var idxs []int
+	for i := range msgs {
+		if batchErr[i] != nil {
+			idxs = append(idxs, i)
+		}
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(idxs, want) {
+		t.Errorf("BatchConsume() failed messages %v, want %v", idxs, want)
+	}
+}
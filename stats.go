@@ -33,19 +33,36 @@ func (*NopStatsHandler) TagProcess(ctx context.Context, t Tag) context.Context {
 func (*NopStatsHandler) HandleProcess(context.Context, Stats) {}
 
 // BeginTag is  tag for an receive/consume process starts.
-type BeginTag struct{}
+type BeginTag struct {
+	Label string
+}
 
 func (*BeginTag) isTag() {}
 
 // End contains stats when an receive/consume process ends.
 type End struct {
 	MsgCount  int
-	BeginTime time.Time
-	EndTime   time.Time
+	AckCount  int
+	NackCount int
+	DropCount int
+	// AckErrorCount is the number of acknowledgements that failed.
+	// It is counted only for messages implementing ResultAcknowledger.
+	AckErrorCount int
+	BeginTime     time.Time
+	EndTime       time.Time
 }
 
 func (*End) isStats() {}
 
+// AckError contains stats when an acknowledgement of a message implementing ResultAcknowledger fails.
+type AckError struct {
+	// Ack is true for an ACK, and false for a NACK.
+	Ack   bool
+	Error error
+}
+
+func (*AckError) isStats() {}
+
 // ConsumeBeginTag is tag for consumption start.
 type ConsumeBeginTag struct{}
 
@@ -69,6 +86,18 @@ func (*EnqueueTag) isTag() {}
 type Dequeue struct {
 	BeginTime time.Time
 	EndTime   time.Time
+	// PublishTime is the earliest publish time of the messages implementing ExtendedMessage, or zero if unknown.
+	// EndTime - PublishTime is the lag from publishing to consumption.
+	PublishTime time.Time
 }
 
 func (*Dequeue) isStats() {}
+
+// ConcurrencyWait contains stats when a consumption slot is acquired.
+type ConcurrencyWait struct {
+	BeginTime time.Time
+	EndTime   time.Time
+	InFlight  int
+}
+
+func (*ConcurrencyWait) isStats() {}
@@ -1,8 +1,12 @@
 module github.com/wantedly/subee/stats/newrelic
 
-go 1.13
+go 1.21
 
 require (
 	github.com/newrelic/go-agent/v3 v3.2.0
 	github.com/wantedly/subee v0.5.0
 )
+
+require github.com/pkg/errors v0.9.1 // indirect
+
+replace github.com/wantedly/subee => ../..
@@ -51,6 +51,9 @@ func (sh *statsHandler) HandleProcess(ctx context.Context, s subee.Stats) {
 	switch s := s.(type) {
 	case *subee.Dequeue:
 		ctx.Value(queueContextKey{}).(*newrelic.Segment).End()
+		if !s.PublishTime.IsZero() {
+			txn.AddAttribute("messageLagMillis", s.EndTime.Sub(s.PublishTime).Milliseconds())
+		}
 
 	case *subee.ConsumeEnd:
 		ctx.Value(consumeContextKey{}).(*newrelic.Segment).End()
@@ -58,6 +61,9 @@ func (sh *statsHandler) HandleProcess(ctx context.Context, s subee.Stats) {
 			txn.NoticeError(s.Error)
 		}
 
+	case *subee.AckError:
+		txn.NoticeError(s.Error)
+
 	case *subee.End:
 		if s.MsgCount > 0 {
 			txn.End()
@@ -5,6 +5,10 @@ import (
 )
 
 // Subscriber is the interface to subscribe message.
+//
+// A Subscriber may implement io.Closer to release its resources, such as connections to the broker.
+// Engine calls Close once Start returns and all received messages are settled,
+// so Close should release only the resources owned by the Subscriber.
 type Subscriber interface {
 	Subscribe(context.Context, func(Message)) error
 }
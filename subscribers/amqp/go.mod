@@ -0,0 +1,12 @@
+module github.com/wantedly/subee/subscribers/amqp
+
+go 1.21
+
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/pkg/errors v0.9.1
+	github.com/rabbitmq/amqp091-go v1.10.0
+	github.com/wantedly/subee v0.5.0
+)
+
+replace github.com/wantedly/subee => ../..
@@ -0,0 +1,122 @@
+package amqp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Message is wrapps amqp.Delivery
+type Message struct {
+	amqp.Delivery
+	metadata   map[string]string
+	policy     RequeuePolicy
+	settleOnce sync.Once
+	settled    func()
+}
+
+func newMessage(d amqp.Delivery, policy RequeuePolicy, settled func()) *Message {
+	return &Message{
+		Delivery: d,
+		metadata: convertTable(d.Headers),
+		policy:   policy,
+		settled:  settled,
+	}
+}
+
+// Data returns the message body.
+func (m *Message) Data() []byte { return m.Body }
+
+// Metadata returns message headers converted into strings.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// MessageID returns the message-id property.
+func (m *Message) MessageID() string { return m.MessageId }
+
+// PublishTime returns the timestamp property.
+func (m *Message) PublishTime() time.Time { return m.Timestamp }
+
+// DeliveryAttempt returns the number of deliveries counted by the x-delivery-count header of quorum queues.
+// It returns 1 for the first delivery, and 0 if the message has been redelivered but the count is unknown.
+func (m *Message) DeliveryAttempt() int {
+	switch n := m.Headers["x-delivery-count"].(type) {
+	case int64:
+		return int(n) + 1
+	case int32:
+		return int(n) + 1
+	case int:
+		return n + 1
+	}
+	if !m.Redelivered {
+		return 1
+	}
+	return 0
+}
+
+// OrderingKey returns an empty string because queues are ordered by themselves.
+func (m *Message) OrderingKey() string { return "" }
+
+// Ack acknowledges the delivery.
+func (m *Message) Ack() {
+	m.settleOnce.Do(func() {
+		m.Delivery.Ack(false)
+		m.settled()
+	})
+}
+
+// Nack negatively acknowledges the delivery, and requeues it according to the RequeuePolicy.
+func (m *Message) Nack() {
+	m.settleOnce.Do(func() {
+		m.Delivery.Nack(false, m.requeue())
+		m.settled()
+	})
+}
+
+func (m *Message) requeue() bool {
+	switch m.policy {
+	case RequeueNever:
+		return false
+	case RequeueOnce:
+		return !m.Redelivered
+	default:
+		return true
+	}
+}
+
+// convertTable converts header values into strings.
+// Nested tables and arrays are encoded in JSON.
+func convertTable(t amqp.Table) map[string]string {
+	m := make(map[string]string, len(t))
+	for k, v := range t {
+		m[k] = convertValue(v)
+	}
+	return m
+}
+
+func convertValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case amqp.Decimal:
+		return strconv.FormatFloat(float64(v.Value)/math.Pow10(int(v.Scale)), 'f', int(v.Scale), 64)
+	case amqp.Table, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
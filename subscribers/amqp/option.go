@@ -0,0 +1,107 @@
+package amqp
+
+import (
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// DefaultPrefetchCount is the default number of unacked messages delivered by the broker.
+	DefaultPrefetchCount = 10
+	// DefaultReconnectInterval is the default interval to reconnect to the broker.
+	DefaultReconnectInterval = 5 * time.Second
+	// DefaultDrainTimeout is the default duration to wait for in-flight messages before closing the connection.
+	DefaultDrainTimeout = 30 * time.Second
+)
+
+// RequeuePolicy represents whether nacked messages are requeued.
+type RequeuePolicy int
+
+const (
+	// RequeueAlways requeues nacked messages.
+	RequeueAlways RequeuePolicy = iota
+	// RequeueOnce requeues nacked messages unless they have been redelivered,
+	// so that they are dead-lettered when they fail twice.
+	RequeueOnce
+	// RequeueNever rejects nacked messages without requeue, so that they are dead-lettered or discarded.
+	RequeueNever
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	AMQPConfig        amqp.Config
+	ConsumerTag       string
+	PrefetchCount     int
+	RequeuePolicy     RequeuePolicy
+	ReconnectInterval time.Duration
+	DrainTimeout      time.Duration
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		PrefetchCount:     DefaultPrefetchCount,
+		ReconnectInterval: DefaultReconnectInterval,
+		DrainTimeout:      DefaultDrainTimeout,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithAMQPConfig returns an Option that set amqp.Config to connect to the broker.
+func WithAMQPConfig(cfg amqp.Config) Option {
+	return func(c *Config) {
+		c.AMQPConfig = cfg
+	}
+}
+
+// WithConsumerTag returns an Option that set the consumer tag.
+// A unique tag is generated by default.
+func WithConsumerTag(tag string) Option {
+	return func(c *Config) {
+		c.ConsumerTag = tag
+	}
+}
+
+// WithPrefetchCount returns an Option that set the number of unacked messages delivered by the broker.
+func WithPrefetchCount(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.PrefetchCount = n
+		}
+	}
+}
+
+// WithRequeuePolicy returns an Option that set whether nacked messages are requeued.
+// RequeueAlways is used by default.
+func WithRequeuePolicy(policy RequeuePolicy) Option {
+	return func(c *Config) {
+		c.RequeuePolicy = policy
+	}
+}
+
+// WithReconnectInterval returns an Option that set the interval to reconnect when the connection or the channel is closed.
+func WithReconnectInterval(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.ReconnectInterval = d
+		}
+	}
+}
+
+// WithDrainTimeout returns an Option that set the duration to wait for in-flight messages to be acked or nacked
+// before closing the connection on Close.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.DrainTimeout = d
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package amqp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/wantedly/subee"
+)
+
+// connection is the subset of *amqp.Connection used by the subscriber.
+type connection interface {
+	Channel() (channel, error)
+	Close() error
+}
+
+// channel is the subset of *amqp.Channel used by the subscriber.
+type channel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+}
+
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c *amqpConnection) Channel() (channel, error) {
+	return c.Connection.Channel()
+}
+
+type subscriberImpl struct {
+	*Config
+	url   string
+	queue string
+	dial  func(url string, cfg amqp.Config) (connection, error)
+
+	mu      sync.Mutex
+	conn    connection
+	settled *sync.WaitGroup
+}
+
+// CreateSubscriber returns Subscriber implementation that consumes the queue.
+// The connection and the channel are recovered automatically when they are closed after the first connection succeeds.
+// The connection is closed by Close after the received messages are acked or nacked or DrainTimeout elapses.
+func CreateSubscriber(url, queue string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if len(url) == 0 {
+		return nil, errors.New("missing amqp url")
+	}
+	if len(queue) == 0 {
+		return nil, errors.New("missing amqp queue name")
+	}
+	if len(cfg.ConsumerTag) == 0 {
+		// The tag is required to cancel the consumer on shutdown.
+		tag, err := generateConsumerTag()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cfg.ConsumerTag = tag
+	}
+
+	return &subscriberImpl{
+		Config: cfg,
+		url:    url,
+		queue:  queue,
+		dial: func(url string, cfg amqp.Config) (connection, error) {
+			conn, err := amqp.DialConfig(url, cfg)
+			if err != nil {
+				return nil, err
+			}
+			return &amqpConnection{conn}, nil
+		},
+	}, nil
+}
+
+func generateConsumerTag() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate amqp consumer tag")
+	}
+	return "subee-" + hex.EncodeToString(b), nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	for attempt := 0; ; attempt++ {
+		conn, ch, deliveries, err := s.connect()
+		if err != nil {
+			if attempt == 0 {
+				return errors.WithStack(err)
+			}
+		} else {
+			if s.consume(ctx, conn, ch, deliveries, f) {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.ReconnectInterval):
+		}
+	}
+}
+
+func (s *subscriberImpl) connect() (connection, channel, <-chan amqp.Delivery, error) {
+	conn, err := s.dial(s.url, s.AMQPConfig)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to connect to amqp broker")
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, errors.Wrap(err, "failed to open amqp channel")
+	}
+
+	if err := ch.Qos(s.PrefetchCount, 0, false); err != nil {
+		conn.Close()
+		return nil, nil, nil, errors.Wrap(err, "failed to set amqp qos")
+	}
+
+	deliveries, err := ch.Consume(s.queue, s.ConsumerTag, false, false, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, errors.Wrapf(err, "failed to consume amqp queue %q", s.queue)
+	}
+
+	return conn, ch, deliveries, nil
+}
+
+// consume delivers messages until ctx is done or the deliveries are closed.
+// It returns true when ctx is done.
+func (s *subscriberImpl) consume(ctx context.Context, conn connection, ch channel, deliveries <-chan amqp.Delivery, f func(subee.Message)) bool {
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			ch.Cancel(s.ConsumerTag, false)
+			// Deliveries are acked or nacked on the channel after Subscribe returns, so the connection is kept open until Close.
+			s.mu.Lock()
+			s.conn, s.settled = conn, &wg
+			s.mu.Unlock()
+			return true
+
+		case d, ok := <-deliveries:
+			if !ok {
+				conn.Close()
+				return false
+			}
+			wg.Add(1)
+			f(newMessage(d, s.RequeuePolicy, wg.Done))
+		}
+	}
+}
+
+// Close closes the connection after the received messages are acked or nacked or DrainTimeout elapses.
+func (s *subscriberImpl) Close() error {
+	s.mu.Lock()
+	conn, settled := s.conn, s.settled
+	s.conn, s.settled = nil, nil
+	s.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		settled.Wait()
+		close(doneCh)
+	}()
+
+	timer := time.NewTimer(s.DrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-doneCh:
+	case <-timer.C:
+	}
+
+	return errors.Wrap(conn.Close(), "failed to close amqp connection")
+}
@@ -0,0 +1,318 @@
+package amqp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/wantedly/subee"
+)
+
+func TestSubscriber(t *testing.T) {
+	conn := newFakeConnection()
+	s := newTestSubscriber(t, conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	msgCh := make(chan subee.Message)
+	go func() {
+		errCh <- s.Subscribe(ctx, func(msg subee.Message) { msgCh <- msg })
+	}()
+
+	ts := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	conn.ch.deliver(amqp.Delivery{
+		DeliveryTag: 1,
+		Body:        []byte("foo"),
+		Headers: amqp.Table{
+			"string":  "bar",
+			"int":     int32(12),
+			"bool":    true,
+			"time":    ts,
+			"decimal": amqp.Decimal{Scale: 2, Value: 1234},
+			"table":   amqp.Table{"baz": "qux"},
+		},
+	})
+	msg := <-msgCh
+
+	if got, want := string(msg.Data()), "foo"; got != want {
+		t.Errorf("Message.Data() is %q, want %q", got, want)
+	}
+	wantMeta := map[string]string{
+		"string":  "bar",
+		"int":     "12",
+		"bool":    "true",
+		"time":    "2019-01-02T03:04:05Z",
+		"decimal": "12.34",
+		"table":   `{"baz":"qux"}`,
+	}
+	if diff := cmp.Diff(wantMeta, msg.Metadata()); diff != "" {
+		t.Errorf("Message.Metadata() differs: (-want +got)\n%s", diff)
+	}
+	if got, want := conn.ch.prefetch, DefaultPrefetchCount; got != want {
+		t.Errorf("prefetch count is %d, want %d", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+	if !conn.ch.canceled() {
+		t.Error("consumer was not canceled")
+	}
+	if conn.closed() {
+		t.Error("connection was closed before the message was settled")
+	}
+
+	msg.Ack()
+	if diff := cmp.Diff([]ackRecord{{Tag: 1, Ack: true}}, conn.ch.acks()); diff != "" {
+		t.Errorf("acks differ: (-want +got)\n%s", diff)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+	if !conn.closed() {
+		t.Error("connection was not closed")
+	}
+}
+
+func TestSubscriber_CloseAfterDrainTimeout(t *testing.T) {
+	conn := newFakeConnection()
+	s := newTestSubscriber(t, conn)
+	s.DrainTimeout = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	msgCh := make(chan subee.Message)
+	go func() {
+		errCh <- s.Subscribe(ctx, func(msg subee.Message) { msgCh <- msg })
+	}()
+
+	conn.ch.deliver(amqp.Delivery{DeliveryTag: 1, Body: []byte("foo")})
+	<-msgCh
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+	if !conn.closed() {
+		t.Error("connection was not closed after the drain timeout")
+	}
+}
+
+func TestSubscriber_RequeuePolicy(t *testing.T) {
+	tests := []struct {
+		policy      RequeuePolicy
+		redelivered bool
+		requeue     bool
+	}{
+		{policy: RequeueAlways, redelivered: true, requeue: true},
+		{policy: RequeueOnce, redelivered: false, requeue: true},
+		{policy: RequeueOnce, redelivered: true, requeue: false},
+		{policy: RequeueNever, redelivered: false, requeue: false},
+	}
+
+	for _, test := range tests {
+		ch := newFakeChannel()
+		newMessage(amqp.Delivery{
+			Acknowledger: ch,
+			DeliveryTag:  1,
+			Redelivered:  test.redelivered,
+		}, test.policy, func() {}).Nack()
+
+		want := []ackRecord{{Tag: 1, Requeue: test.requeue}}
+		if diff := cmp.Diff(want, ch.acks()); diff != "" {
+			t.Errorf("nacks with policy %d differ: (-want +got)\n%s", test.policy, diff)
+		}
+	}
+}
+
+func TestSubscriber_Reconnect(t *testing.T) {
+	conns := []*fakeConnection{newFakeConnection(), newFakeConnection()}
+	s := newTestSubscriber(t, conns...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	msgCh := make(chan subee.Message)
+	go func() {
+		errCh <- s.Subscribe(ctx, func(msg subee.Message) { msgCh <- msg })
+	}()
+
+	conns[0].ch.deliver(amqp.Delivery{DeliveryTag: 1, Body: []byte("foo")})
+	<-msgCh
+	conns[0].ch.close()
+
+	conns[1].ch.deliver(amqp.Delivery{DeliveryTag: 1, Body: []byte("bar")})
+	if got, want := string((<-msgCh).Data()), "bar"; got != want {
+		t.Errorf("Message.Data() is %q, want %q", got, want)
+	}
+	if !conns[0].closed() {
+		t.Error("broken connection was not closed")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+}
+
+func TestSubscriber_WhenFirstConnectionFailed(t *testing.T) {
+	s := newTestSubscriber(t)
+
+	if err := s.Subscribe(context.Background(), func(subee.Message) {}); err == nil {
+		t.Error("Subscribe returned nil, want an error")
+	}
+}
+
+func newTestSubscriber(t *testing.T, conns ...*fakeConnection) *subscriberImpl {
+	t.Helper()
+
+	sub, err := CreateSubscriber("amqp://localhost", "test-queue", WithReconnectInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	var mu sync.Mutex
+	s := sub.(*subscriberImpl)
+	s.dial = func(string, amqp.Config) (connection, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(conns) == 0 {
+			return nil, errors.New("connection refused")
+		}
+		conn := conns[0]
+		conns = conns[1:]
+		return conn, nil
+	}
+
+	return s
+}
+
+type fakeConnection struct {
+	ch *fakeChannel
+
+	mu       sync.Mutex
+	isClosed bool
+}
+
+func newFakeConnection() *fakeConnection {
+	return &fakeConnection{ch: newFakeChannel()}
+}
+
+func (c *fakeConnection) Channel() (channel, error) { return c.ch, nil }
+
+func (c *fakeConnection) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isClosed = true
+	return nil
+}
+
+func (c *fakeConnection) closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isClosed
+}
+
+type ackRecord struct {
+	Tag     uint64
+	Ack     bool
+	Requeue bool
+}
+
+// fakeChannel implements channel and amqp.Acknowledger.
+type fakeChannel struct {
+	deliveries chan amqp.Delivery
+	closeOnce  sync.Once
+	prefetch   int
+
+	mu         sync.Mutex
+	records    []ackRecord
+	isCanceled bool
+}
+
+func newFakeChannel() *fakeChannel {
+	return &fakeChannel{deliveries: make(chan amqp.Delivery)}
+}
+
+func (c *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	c.prefetch = prefetchCount
+	return nil
+}
+
+func (c *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	return c.deliveries, nil
+}
+
+func (c *fakeChannel) Cancel(consumer string, noWait bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isCanceled = true
+	return nil
+}
+
+func (c *fakeChannel) Ack(tag uint64, multiple bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, ackRecord{Tag: tag, Ack: true})
+	return nil
+}
+
+func (c *fakeChannel) Nack(tag uint64, multiple bool, requeue bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, ackRecord{Tag: tag, Requeue: requeue})
+	return nil
+}
+
+func (c *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return c.Nack(tag, false, requeue)
+}
+
+func (c *fakeChannel) deliver(d amqp.Delivery) {
+	d.Acknowledger = c
+	c.deliveries <- d
+}
+
+func (c *fakeChannel) close() {
+	c.closeOnce.Do(func() { close(c.deliveries) })
+}
+
+func (c *fakeChannel) acks() []ackRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ackRecord(nil), c.records...)
+}
+
+func (c *fakeChannel) canceled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isCanceled
+}
+
+func TestMessage_DeliveryAttempt(t *testing.T) {
+	cases := []struct {
+		delivery amqp.Delivery
+		want     int
+	}{
+		{delivery: amqp.Delivery{}, want: 1},
+		{delivery: amqp.Delivery{Redelivered: true}, want: 0},
+		{delivery: amqp.Delivery{Redelivered: true, Headers: amqp.Table{"x-delivery-count": int64(2)}}, want: 3},
+	}
+
+	for _, c := range cases {
+		if got := newMessage(c.delivery, RequeueAlways, func() {}).DeliveryAttempt(); got != c.want {
+			t.Errorf("DeliveryAttempt() of %+v returned %d, want %d", c.delivery, got, c.want)
+		}
+	}
+}
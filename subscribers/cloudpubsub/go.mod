@@ -1,12 +1,38 @@
 module github.com/wantedly/subee/subscribers/cloudpubsub
 
-go 1.11
+go 1.21
 
 require (
-	cloud.google.com/go/pubsub v1.0.1
-	github.com/google/go-cmp v0.3.1
-	github.com/pkg/errors v0.8.1
+	cloud.google.com/go/pubsub v1.33.0
+	github.com/google/go-cmp v0.5.9
+	github.com/pkg/errors v0.9.1
 	github.com/wantedly/subee v0.5.0
-	google.golang.org/api v0.11.0
-	google.golang.org/grpc v1.24.0
+	google.golang.org/api v0.126.0
+	google.golang.org/grpc v1.55.0
 )
+
+require (
+	cloud.google.com/go v0.110.2 // indirect
+	cloud.google.com/go/compute v1.19.3 // indirect
+	cloud.google.com/go/compute/metadata v0.2.3 // indirect
+	cloud.google.com/go/iam v1.1.0 // indirect
+	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/google/s2a-go v0.1.4 // indirect
+	github.com/googleapis/enterprise-certificate-proxy v0.2.3 // indirect
+	github.com/googleapis/gax-go/v2 v2.11.0 // indirect
+	go.opencensus.io v0.24.0 // indirect
+	golang.org/x/crypto v0.9.0 // indirect
+	golang.org/x/net v0.10.0 // indirect
+	golang.org/x/oauth2 v0.8.0 // indirect
+	golang.org/x/sync v0.2.0 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20230530153820-e85fd2cbaebc // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20230530153820-e85fd2cbaebc // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230530153820-e85fd2cbaebc // indirect
+	google.golang.org/protobuf v1.30.0 // indirect
+)
+
+replace github.com/wantedly/subee => ../..
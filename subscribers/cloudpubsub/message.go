@@ -1,6 +1,13 @@
 package cloudpubsub
 
-import "cloud.google.com/go/pubsub"
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
 
 // Message is wrapps *pubsub.Message
 type Message struct {
@@ -12,3 +19,53 @@ func (m *Message) Data() []byte { return m.Message.Data }
 
 // Metadata returns message attributes.
 func (m *Message) Metadata() map[string]string { return m.Message.Attributes }
+
+// MessageID returns *pubsub.Message.ID
+func (m *Message) MessageID() string { return m.Message.ID }
+
+// PublishTime returns *pubsub.Message.PublishTime
+func (m *Message) PublishTime() time.Time { return m.Message.PublishTime }
+
+// DeliveryAttempt returns *pubsub.Message.DeliveryAttempt.
+// It is 0 unless a dead-letter policy is set on the subscription.
+func (m *Message) DeliveryAttempt() int {
+	if m.Message.DeliveryAttempt == nil {
+		return 0
+	}
+	return *m.Message.DeliveryAttempt
+}
+
+// OrderingKey returns *pubsub.Message.OrderingKey
+func (m *Message) OrderingKey() string { return m.Message.OrderingKey }
+
+// ExactlyOnceMessage is wrapps *pubsub.Message delivered from a subscription with exactly-once delivery enabled.
+// It implements subee.ResultAcknowledger.
+type ExactlyOnceMessage struct {
+	*Message
+}
+
+// AckWithResult acknowledges the message and returns the result.
+func (m *ExactlyOnceMessage) AckWithResult() subee.AckResult {
+	return &ackResult{m.Message.Message.AckWithResult()}
+}
+
+// NackWithResult negatively acknowledges the message and returns the result.
+func (m *ExactlyOnceMessage) NackWithResult() subee.AckResult {
+	return &ackResult{m.Message.Message.NackWithResult()}
+}
+
+type ackResult struct {
+	*pubsub.AckResult
+}
+
+// Get waits for the acknowledgement to complete, and returns an error unless it succeeded.
+func (r *ackResult) Get(ctx context.Context) error {
+	status, err := r.AckResult.Get(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if status != pubsub.AcknowledgeStatusSuccess {
+		return errors.Errorf("acknowledgement failed with status %v", status)
+	}
+	return nil
+}
@@ -7,8 +7,19 @@ import (
 
 // Config represents subscriber configuration.
 type Config struct {
+	Client          *pubsub.Client
 	ClientOpts      []option.ClientOption
 	ReceiveSettings pubsub.ReceiveSettings
+
+	ExactlyOnceDelivery bool
+
+	CreateIfMissing *Provisioning
+}
+
+// Provisioning represents the topic and the subscription created when they are missing.
+type Provisioning struct {
+	TopicID            string
+	SubscriptionConfig pubsub.SubscriptionConfig
 }
 
 func (c *Config) apply(opts []Option) {
@@ -20,6 +31,14 @@ func (c *Config) apply(opts []Option) {
 // Option is subscriber Option
 type Option func(*Config)
 
+// WithClient returns an Option that set the existing *pubsub.Client.
+// The client is not closed by the subscriber, and the project ID given to CreateSubscriber is ignored.
+func WithClient(client *pubsub.Client) Option {
+	return func(c *Config) {
+		c.Client = client
+	}
+}
+
 // WithClientOptions returns an Option that set option.ClientOption implementation(s).
 func WithClientOptions(opts ...option.ClientOption) Option {
 	return func(c *Config) {
@@ -33,3 +52,25 @@ func WithReceiveSettings(cfg pubsub.ReceiveSettings) Option {
 		c.ReceiveSettings = cfg
 	}
 }
+
+// WithExactlyOnceDelivery returns an Option that acknowledges messages with AckWithResult and NackWithResult.
+// It should be set for subscriptions with exactly-once delivery enabled, so that failed acknowledgements are reported by the Engine.
+func WithExactlyOnceDelivery() Option {
+	return func(c *Config) {
+		c.ExactlyOnceDelivery = true
+	}
+}
+
+// WithCreateIfMissing returns an Option that creates the topic and the subscription when they do not exist.
+// The Topic of cfg is set to the topic, and the other fields such as AckDeadline, RetentionDuration, Filter,
+// DeadLetterPolicy and RetryPolicy configure the subscription.
+// When the subscription exists, CreateSubscriber fails if the non-zero fields of cfg differ from its config.
+// It is intended for local development with the Pub/Sub emulator.
+func WithCreateIfMissing(topicID string, cfg pubsub.SubscriptionConfig) Option {
+	return func(c *Config) {
+		c.CreateIfMissing = &Provisioning{
+			TopicID:            topicID,
+			SubscriptionConfig: cfg,
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cloudpubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// provisionSubscription creates the topic if it does not exist, and then creates the subscription.
+func provisionSubscription(ctx context.Context, subscriptionID string, c *pubsub.Client, p *Provisioning) (*pubsub.Subscription, error) {
+	if len(p.TopicID) == 0 {
+		return nil, errors.New("missing pub/sub topic id to create the subscription")
+	}
+
+	topic := c.Topic(p.TopicID)
+
+	ok, err := topic.Exists(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to report whether the topic exists on the server")
+	}
+	if !ok {
+		_, err := c.CreateTopic(ctx, p.TopicID)
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return nil, errors.Wrapf(err, "failed to create pub/sub topic %q", p.TopicID)
+		}
+	}
+
+	cfg := p.SubscriptionConfig
+	cfg.Topic = topic
+
+	pubsubSub, err := c.CreateSubscription(ctx, subscriptionID, cfg)
+	if status.Code(err) == codes.AlreadyExists {
+		// The subscription was created by another process after the existence check.
+		pubsubSub = c.Subscription(subscriptionID)
+		if err := validateSubscription(ctx, pubsubSub, c, p); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return pubsubSub, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create pub/sub subscription %q", subscriptionID)
+	}
+
+	return pubsubSub, nil
+}
+
+// validateSubscription returns an error if the existing subscription drifts from the provisioning config.
+func validateSubscription(ctx context.Context, s *pubsub.Subscription, c *pubsub.Client, p *Provisioning) error {
+	got, err := s.Config(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the config of pub/sub subscription %q", s.ID())
+	}
+
+	want := p.SubscriptionConfig
+	if len(p.TopicID) > 0 {
+		want.Topic = c.Topic(p.TopicID)
+	}
+
+	if diffs := diffSubscriptionConfig(got, want); len(diffs) > 0 {
+		return errors.Errorf("pub/sub subscription %q differs from the config to create it: %s", s.ID(), strings.Join(diffs, ", "))
+	}
+
+	return nil
+}
+
+// diffSubscriptionConfig describes the differences of the non-zero fields of want from got.
+func diffSubscriptionConfig(got, want pubsub.SubscriptionConfig) (diffs []string) {
+	diff := func(field string, got, want interface{}) {
+		diffs = append(diffs, fmt.Sprintf("%s is %v, want %v", field, got, want))
+	}
+
+	if want.Topic != nil && (got.Topic == nil || got.Topic.String() != want.Topic.String()) {
+		gotTopic := "<nil>"
+		if got.Topic != nil {
+			gotTopic = got.Topic.String()
+		}
+		diff("topic", gotTopic, want.Topic.String())
+	}
+	if want.AckDeadline != 0 && got.AckDeadline != want.AckDeadline {
+		diff("ack deadline", got.AckDeadline, want.AckDeadline)
+	}
+	if want.RetentionDuration != 0 && got.RetentionDuration != want.RetentionDuration {
+		diff("retention duration", got.RetentionDuration, want.RetentionDuration)
+	}
+	if want.RetainAckedMessages && !got.RetainAckedMessages {
+		diff("retain acked messages", got.RetainAckedMessages, want.RetainAckedMessages)
+	}
+	if want.Filter != "" && got.Filter != want.Filter {
+		diff("filter", fmt.Sprintf("%q", got.Filter), fmt.Sprintf("%q", want.Filter))
+	}
+	if want.EnableMessageOrdering && !got.EnableMessageOrdering {
+		diff("message ordering", got.EnableMessageOrdering, want.EnableMessageOrdering)
+	}
+	if want.EnableExactlyOnceDelivery && !got.EnableExactlyOnceDelivery {
+		diff("exactly-once delivery", got.EnableExactlyOnceDelivery, want.EnableExactlyOnceDelivery)
+	}
+	if want.DeadLetterPolicy != nil && (got.DeadLetterPolicy == nil || *got.DeadLetterPolicy != *want.DeadLetterPolicy) {
+		diff("dead letter policy", formatDeadLetterPolicy(got.DeadLetterPolicy), formatDeadLetterPolicy(want.DeadLetterPolicy))
+	}
+	if want.RetryPolicy != nil && (got.RetryPolicy == nil || !equalRetryPolicy(got.RetryPolicy, want.RetryPolicy)) {
+		diff("retry policy", formatRetryPolicy(got.RetryPolicy), formatRetryPolicy(want.RetryPolicy))
+	}
+
+	return diffs
+}
+
+func formatDeadLetterPolicy(p *pubsub.DeadLetterPolicy) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("{topic: %s, max delivery attempts: %d}", p.DeadLetterTopic, p.MaxDeliveryAttempts)
+}
+
+// equalRetryPolicy reports whether the backoffs of got equal to the ones set in want.
+func equalRetryPolicy(got, want *pubsub.RetryPolicy) bool {
+	if d := duration(want.MinimumBackoff); d != 0 && duration(got.MinimumBackoff) != d {
+		return false
+	}
+	if d := duration(want.MaximumBackoff); d != 0 && duration(got.MaximumBackoff) != d {
+		return false
+	}
+	return true
+}
+
+func formatRetryPolicy(p *pubsub.RetryPolicy) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("{minimum backoff: %v, maximum backoff: %v}", duration(p.MinimumBackoff), duration(p.MaximumBackoff))
+}
+
+// duration returns the value of optional.Duration, or zero if it is not set.
+func duration(v interface{}) time.Duration {
+	d, _ := v.(time.Duration)
+	return d
+}
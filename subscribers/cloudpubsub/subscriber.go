@@ -11,25 +11,37 @@ import (
 
 type subscriberImpl struct {
 	*Config
+	client       *pubsub.Client
+	ownsClient   bool
 	subscription *pubsub.Subscription
 }
 
 // CreateSubscriber returns Subscriber implementation.
+// The subscriber creates its own client unless WithClient is given, and closes it on Close.
+// To consume messages with the same ordering key in order, enable message ordering on the subscription
+// and create the Engine with subee.WithOrderingKey(subee.OrderingKeyFromMessage).
+// For subscriptions with exactly-once delivery enabled, use WithExactlyOnceDelivery.
 func CreateSubscriber(ctx context.Context, projectID, subscriptionID string, opts ...Option) (subee.Subscriber, error) {
 	cfg := new(Config)
 	cfg.apply(opts)
 
 	sub := &subscriberImpl{
 		Config: cfg,
+		client: cfg.Client,
 	}
 
-	c, err := createPubSubClient(ctx, projectID, sub.ClientOpts...)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create Google Cloud Pub/Sub client")
+	if sub.client == nil {
+		c, err := createPubSubClient(ctx, projectID, sub.ClientOpts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create Google Cloud Pub/Sub client")
+		}
+		sub.client = c
+		sub.ownsClient = true
 	}
 
-	pubsubSub, err := createPubSubSubscription(ctx, subscriptionID, c)
+	pubsubSub, err := createPubSubSubscription(ctx, subscriptionID, sub.client, cfg.CreateIfMissing)
 	if err != nil {
+		sub.Close()
 		return nil, errors.Wrap(err, "failed to create Google Cloud Pub/Sub subscription")
 	}
 	sub.subscription = pubsubSub
@@ -50,7 +62,7 @@ func createPubSubClient(ctx context.Context, projectID string, ops ...option.Cli
 	return c, nil
 }
 
-func createPubSubSubscription(ctx context.Context, subscriptionID string, c *pubsub.Client) (*pubsub.Subscription, error) {
+func createPubSubSubscription(ctx context.Context, subscriptionID string, c *pubsub.Client, p *Provisioning) (*pubsub.Subscription, error) {
 	pubsubSub := c.Subscription(subscriptionID)
 
 	ok, err := pubsubSub.Exists(ctx)
@@ -58,8 +70,19 @@ func createPubSubSubscription(ctx context.Context, subscriptionID string, c *pub
 		return nil, errors.Wrap(err, "failed to report whether the subscription exists on the server")
 	}
 
+	if p == nil {
+		if !ok {
+			return nil, errors.New("failed to get pub/sub subscription. Check subscription presence")
+		}
+		return pubsubSub, nil
+	}
+
 	if !ok {
-		return nil, errors.New("failed to get pub/sub subscription. Check subscription presence")
+		return provisionSubscription(ctx, subscriptionID, c, p)
+	}
+
+	if err := validateSubscription(ctx, pubsubSub, c, p); err != nil {
+		return nil, errors.WithStack(err)
 	}
 
 	return pubsubSub, nil
@@ -67,8 +90,21 @@ func createPubSubSubscription(ctx context.Context, subscriptionID string, c *pub
 
 func (r *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
 	err := r.subscription.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		if r.ExactlyOnceDelivery {
+			f(&ExactlyOnceMessage{&Message{m}})
+			return
+		}
 		f(&Message{m})
 	})
 
 	return errors.WithStack(err)
 }
+
+// Close closes the client created by CreateSubscriber.
+// The client given by WithClient is left open.
+func (r *subscriberImpl) Close() error {
+	if !r.ownsClient {
+		return nil
+	}
+	return errors.WithStack(r.client.Close())
+}
@@ -2,10 +2,12 @@ package cloudpubsub_test
 
 import (
 	"context"
+	"io"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
@@ -45,6 +47,7 @@ func TestSubscriber(t *testing.T) {
 		cloudpubsub.WithClientOptions(option.WithGRPCConn(conn)),
 	)
 	orDie(t, err)
+	defer subscriber.(io.Closer).Close()
 
 	type Msg struct {
 		Data []byte
@@ -90,6 +93,9 @@ func TestSubscriber(t *testing.T) {
 	out := []Msg{}
 	for m := range msgCh {
 		out = append(out, Msg{Data: m.Data(), Meta: m.Metadata()})
+		if em := m.(subee.ExtendedMessage); em.MessageID() == "" || em.PublishTime().IsZero() {
+			t.Errorf("message %q has no ID or publish time", m.Data())
+		}
 	}
 
 	sorter := cmp.Transformer("Sort", func(in []Msg) []Msg {
@@ -104,3 +110,83 @@ func TestSubscriber(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestSubscriber_WithClient(t *testing.T) {
+	ctx := context.Background()
+
+	srv := pstest.NewServer()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	client, err := pubsub.NewClient(ctx, "test-proj", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+	topic, err := client.CreateTopic(ctx, "test-topic")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subscription, err := client.CreateSubscription(ctx, "test-sub", pubsub.SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	subscriber, err := cloudpubsub.CreateSubscriber(ctx, "", subscription.ID(), cloudpubsub.WithClient(client))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+	if err := subscriber.(io.Closer).Close(); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+
+	if _, err := subscription.Exists(ctx); err != nil {
+		t.Errorf("The client should not be closed by the subscriber: %v", err)
+	}
+}
+
+func TestSubscriber_WithCreateIfMissing(t *testing.T) {
+	ctx := context.Background()
+
+	srv := pstest.NewServer()
+	conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	client, err := pubsub.NewClient(ctx, "test-proj", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer client.Close()
+
+	cfg := pubsub.SubscriptionConfig{
+		AckDeadline: 20 * time.Second,
+		RetryPolicy: &pubsub.RetryPolicy{MinimumBackoff: 5 * time.Second},
+	}
+
+	subscriber, err := cloudpubsub.CreateSubscriber(ctx, "", "test-sub", cloudpubsub.WithClient(client), cloudpubsub.WithCreateIfMissing("test-topic", cfg))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+	subscriber.(io.Closer).Close()
+
+	got, err := client.Subscription("test-sub").Config(ctx)
+	if err != nil {
+		t.Fatalf("The subscription should be created: %v", err)
+	}
+	if got.Topic.ID() != "test-topic" || got.AckDeadline != cfg.AckDeadline {
+		t.Errorf("The subscription is created with topic %s and ack deadline %v, want test-topic and %v", got.Topic.ID(), got.AckDeadline, cfg.AckDeadline)
+	}
+
+	if _, err := cloudpubsub.CreateSubscriber(ctx, "", "test-sub", cloudpubsub.WithClient(client), cloudpubsub.WithCreateIfMissing("test-topic", cfg)); err != nil {
+		t.Errorf("CreateSubscriber returned an error for the existing subscription: %v", err)
+	}
+
+	cfg.AckDeadline = 30 * time.Second
+	if _, err := cloudpubsub.CreateSubscriber(ctx, "", "test-sub", cloudpubsub.WithClient(client), cloudpubsub.WithCreateIfMissing("test-topic", cfg)); err == nil {
+		t.Error("CreateSubscriber should return an error when the subscription drifts from the config")
+	}
+}
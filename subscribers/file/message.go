@@ -0,0 +1,63 @@
+package file
+
+import (
+	"sync"
+	"time"
+)
+
+// Message is a message read from a file.
+type Message struct {
+	*Record
+	file string
+	line int
+
+	sub        *Subscriber
+	settleOnce sync.Once
+}
+
+// Data returns the message data.
+func (m *Message) Data() []byte {
+	if len(m.Record.Data) == 0 && m.Text != "" {
+		return []byte(m.Text)
+	}
+	return m.Record.Data
+}
+
+// Metadata returns the message metadata.
+func (m *Message) Metadata() map[string]string { return m.Record.Metadata }
+
+// MessageID returns the recorded ID.
+func (m *Message) MessageID() string { return m.ID }
+
+// PublishTime returns the recorded timestamp.
+func (m *Message) PublishTime() time.Time { return m.Timestamp }
+
+// DeliveryAttempt returns 1 because records are delivered once.
+func (m *Message) DeliveryAttempt() int { return 1 }
+
+// OrderingKey returns the recorded ordering key.
+func (m *Message) OrderingKey() string { return m.Record.OrderingKey }
+
+// File returns the name of the file the message was read from.
+func (m *Message) File() string { return m.file }
+
+// Line returns the line number of the message in the file, starting from 1.
+func (m *Message) Line() int { return m.line }
+
+// Ack writes the ack outcome to the results.
+func (m *Message) Ack() { m.settle(OutcomeAck) }
+
+// Nack writes the nack outcome to the results.
+func (m *Message) Nack() { m.settle(OutcomeNack) }
+
+func (m *Message) settle(outcome string) {
+	m.settleOnce.Do(func() {
+		m.sub.writeResult(&Result{
+			File:    m.file,
+			Line:    m.line,
+			ID:      m.ID,
+			Outcome: outcome,
+			Time:    time.Now().UTC(),
+		})
+	})
+}
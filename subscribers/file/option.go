@@ -0,0 +1,45 @@
+package file
+
+import "io"
+
+// Config represents subscriber configuration.
+type Config struct {
+	ReplaySpeed float64
+	Results     io.Writer
+	ResultsFile string
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithReplaySpeed returns an Option that replays messages honouring their recorded timestamps.
+// The intervals between messages are divided by speed, so 1 replays at the original speed and 2 twice as fast.
+// Messages are delivered as fast as possible by default.
+func WithReplaySpeed(speed float64) Option {
+	return func(c *Config) {
+		if speed > 0 {
+			c.ReplaySpeed = speed
+		}
+	}
+}
+
+// WithResults returns an Option that writes the outcomes of messages to w as JSON Lines.
+func WithResults(w io.Writer) Option {
+	return func(c *Config) {
+		c.Results = w
+	}
+}
+
+// WithResultsFile returns an Option that appends the outcomes of messages to the named file as JSON Lines.
+// The file is closed by Subscriber.Close.
+func WithResultsFile(name string) Option {
+	return func(c *Config) {
+		c.ResultsFile = name
+	}
+}
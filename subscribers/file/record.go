@@ -0,0 +1,32 @@
+package file
+
+import "time"
+
+// Record represents a message in JSON Lines files.
+// It is compatible with letters written by the dead-letter FileSink.
+type Record struct {
+	ID string `json:"id,omitempty"`
+	// Data is the base64 encoded message data.
+	Data []byte `json:"data,omitempty"`
+	// Text is the raw message data used when Data is empty.
+	Text        string            `json:"text,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	OrderingKey string            `json:"ordering_key,omitempty"`
+	Timestamp   time.Time         `json:"timestamp,omitempty"`
+}
+
+// Result represents the outcome of a message written to the results file.
+type Result struct {
+	File    string    `json:"file"`
+	Line    int       `json:"line"`
+	ID      string    `json:"id,omitempty"`
+	Outcome string    `json:"outcome"`
+	Time    time.Time `json:"time"`
+}
+
+const (
+	// OutcomeAck is the outcome of acked messages.
+	OutcomeAck = "ack"
+	// OutcomeNack is the outcome of nacked messages.
+	OutcomeNack = "nack"
+)
@@ -0,0 +1,188 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// Subscriber implements subee.Subscriber that replays messages from JSON Lines files.
+// Subscribe returns after all messages are delivered.
+type Subscriber struct {
+	*Config
+	files []string
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	results io.Closer
+}
+
+// CreateSubscriber returns Subscriber that reads the JSON Lines file, or the *.jsonl files in the directory in name order.
+func CreateSubscriber(path string, opts ...Option) (*Subscriber, error) {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	files, err := listFiles(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &Subscriber{
+		Config: cfg,
+		files:  files,
+	}
+
+	switch {
+	case cfg.ResultsFile != "":
+		f, err := os.OpenFile(cfg.ResultsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open results file")
+		}
+		s.enc = json.NewEncoder(f)
+		s.results = f
+	case cfg.Results != nil:
+		s.enc = json.NewEncoder(cfg.Results)
+	}
+
+	return s, nil
+}
+
+func listFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat messages file")
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read messages directory")
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Subscribe implements subee.Subscriber.Subscribe.
+func (s *Subscriber) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	r := &replayer{speed: s.ReplaySpeed}
+
+	for _, name := range s.files {
+		if err := s.read(ctx, name, r, f); err != nil {
+			return errors.WithStack(err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Subscriber) read(ctx context.Context, name string, r *replayer, f func(subee.Message)) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to open messages file")
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	sc.Buffer(nil, 64<<20)
+
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+
+		rec := new(Record)
+		if err := json.Unmarshal(sc.Bytes(), rec); err != nil {
+			return errors.Wrapf(err, "failed to decode message at %s:%d", name, line)
+		}
+
+		if !r.wait(ctx, rec.Timestamp) {
+			return nil
+		}
+
+		f(&Message{Record: rec, file: name, line: line, sub: s})
+	}
+
+	return errors.Wrapf(sc.Err(), "failed to read messages file %s", name)
+}
+
+func (s *Subscriber) writeResult(r *Result) {
+	if s.enc == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enc.Encode(r)
+}
+
+// Close closes the results file opened by WithResultsFile.
+// Engine calls it when Start returns, after all outcomes are written.
+func (s *Subscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.results == nil {
+		return nil
+	}
+	err := s.results.Close()
+	s.results = nil
+	return errors.WithStack(err)
+}
+
+// replayer paces deliveries with recorded timestamps.
+type replayer struct {
+	speed     float64
+	base      time.Time
+	startedAt time.Time
+}
+
+// wait blocks until the time to deliver the message recorded at ts.
+// It returns false when ctx is done.
+func (r *replayer) wait(ctx context.Context, ts time.Time) bool {
+	if r.speed <= 0 || ts.IsZero() {
+		return ctx.Err() == nil
+	}
+
+	if r.base.IsZero() {
+		r.base, r.startedAt = ts, time.Now()
+		return ctx.Err() == nil
+	}
+
+	d := time.Duration(float64(ts.Sub(r.base))/r.speed) - time.Since(r.startedAt)
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
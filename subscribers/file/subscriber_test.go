@@ -0,0 +1,188 @@
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/file"
+)
+
+func TestSubscriber(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "b.jsonl"), `{"id":"3","text":"baz"}`+"\n")
+	writeFile(t, filepath.Join(dir, "a.jsonl"), `{"id":"1","data":"Zm9v","metadata":{"key":"value"}}`+"\n\n"+`{"id":"2","text":"bar"}`+"\n")
+	writeFile(t, filepath.Join(dir, "ignored.txt"), "not a message\n")
+
+	var results bytes.Buffer
+	sub, err := file.CreateSubscriber(dir, file.WithResults(&results))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	type received struct {
+		Data     string
+		Metadata map[string]string
+		Line     int
+	}
+	var got []received
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		m := msg.(*file.Message)
+		got = append(got, received{Data: string(m.Data()), Metadata: m.Metadata(), Line: m.Line()})
+		if m.ID == "2" {
+			return errors.New("error")
+		}
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)), subee.WithMaxConcurrency(1))
+
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+
+	want := []received{
+		{Data: "foo", Metadata: map[string]string{"key": "value"}, Line: 1},
+		{Data: "bar", Line: 3},
+		{Data: "baz", Line: 1},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("received messages differ: (-want +got)\n%s", diff)
+	}
+
+	wantResults := []file.Result{
+		{File: filepath.Join(dir, "a.jsonl"), Line: 1, ID: "1", Outcome: file.OutcomeAck},
+		{File: filepath.Join(dir, "a.jsonl"), Line: 3, ID: "2", Outcome: file.OutcomeNack},
+		{File: filepath.Join(dir, "b.jsonl"), Line: 1, ID: "3", Outcome: file.OutcomeAck},
+	}
+	if diff := cmp.Diff(wantResults, decodeResults(t, results.Bytes()), cmpopts.IgnoreFields(file.Result{}, "Time")); diff != "" {
+		t.Errorf("results differ: (-want +got)\n%s", diff)
+	}
+}
+
+func TestSubscriber_WithReplaySpeed(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "messages.jsonl")
+	writeFile(t, name, ""+
+		`{"text":"1","timestamp":"2021-01-01T00:00:00Z"}`+"\n"+
+		`{"text":"2","timestamp":"2021-01-01T00:00:02Z"}`+"\n"+
+		`{"text":"3","timestamp":"2021-01-01T00:00:04Z"}`+"\n")
+
+	resultsFile := filepath.Join(t.TempDir(), "results.jsonl")
+	sub, err := file.CreateSubscriber(name, file.WithReplaySpeed(20), file.WithResultsFile(resultsFile))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	var got []time.Time
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		got = append(got, time.Now())
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)), subee.WithMaxConcurrency(1))
+
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("received %d messages, want 3", len(got))
+	}
+	if d := got[2].Sub(got[0]); d < 200*time.Millisecond {
+		t.Errorf("messages were replayed in %v, want at least 200ms", d)
+	}
+
+	data, err := os.ReadFile(resultsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(decodeResults(t, data)), 3; got != want {
+		t.Errorf("results file has %d records, want %d", got, want)
+	}
+}
+
+func TestSubscriber_WhenContextCanceled(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "messages.jsonl")
+	writeFile(t, name, ""+
+		`{"text":"1","timestamp":"2021-01-01T00:00:00Z"}`+"\n"+
+		`{"text":"2","timestamp":"2021-01-01T01:00:00Z"}`+"\n")
+
+	sub, err := file.CreateSubscriber(name, file.WithReplaySpeed(1))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan struct{}, 2)
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		received <- struct{}{}
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+
+	<-received
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("Start returned an error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after the context was canceled")
+	}
+	if got := len(received); got != 0 {
+		t.Errorf("received %d extra messages, want 0", got)
+	}
+}
+
+func TestSubscriber_WhenMalformed(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "messages.jsonl")
+	writeFile(t, name, `{"text":"1"}`+"\n"+`{`+"\n")
+
+	sub, err := file.CreateSubscriber(name)
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Start should return an error")
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func decodeResults(t *testing.T, data []byte) []file.Result {
+	t.Helper()
+	var results []file.Result
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var r file.Result
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, r)
+	}
+	return results
+}
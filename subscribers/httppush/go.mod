@@ -0,0 +1,12 @@
+module github.com/wantedly/subee/subscribers/httppush
+
+go 1.21
+
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/wantedly/subee v0.5.0
+)
+
+require github.com/pkg/errors v0.9.1 // indirect
+
+replace github.com/wantedly/subee => ../..
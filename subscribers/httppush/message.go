@@ -0,0 +1,89 @@
+package httppush
+
+import "time"
+
+// envelope is the JSON body of a push request.
+type envelope struct {
+	Message struct {
+		Data             []byte            `json:"data"`
+		Attributes       map[string]string `json:"attributes"`
+		MessageID        string            `json:"messageId"`
+		MessageIDSnake   string            `json:"message_id"`
+		PublishTime      time.Time         `json:"publishTime"`
+		PublishTimeSnake time.Time         `json:"publish_time"`
+		OrderingKey      string            `json:"orderingKey"`
+	} `json:"message"`
+	Subscription    string `json:"subscription"`
+	DeliveryAttempt int    `json:"deliveryAttempt"`
+}
+
+// Message is a message delivered by a push request.
+type Message struct {
+	data            []byte
+	attributes      map[string]string
+	id              string
+	publishTime     time.Time
+	orderingKey     string
+	subscription    string
+	deliveryAttempt int
+
+	resultCh chan bool
+}
+
+func newMessage(e *envelope) *Message {
+	m := &Message{
+		data:            e.Message.Data,
+		attributes:      e.Message.Attributes,
+		id:              e.Message.MessageID,
+		publishTime:     e.Message.PublishTime,
+		orderingKey:     e.Message.OrderingKey,
+		subscription:    e.Subscription,
+		deliveryAttempt: e.DeliveryAttempt,
+		resultCh:        make(chan bool, 1),
+	}
+	if m.id == "" {
+		m.id = e.Message.MessageIDSnake
+	}
+	if m.publishTime.IsZero() {
+		m.publishTime = e.Message.PublishTimeSnake
+	}
+	if m.attributes == nil {
+		m.attributes = map[string]string{}
+	}
+	return m
+}
+
+// Data returns the decoded message data.
+func (m *Message) Data() []byte { return m.data }
+
+// Metadata returns message attributes.
+func (m *Message) Metadata() map[string]string { return m.attributes }
+
+// MessageID returns the message ID.
+func (m *Message) MessageID() string { return m.id }
+
+// PublishTime returns the time when the message was published.
+func (m *Message) PublishTime() time.Time { return m.publishTime }
+
+// OrderingKey returns the ordering key of the message.
+func (m *Message) OrderingKey() string { return m.orderingKey }
+
+// Subscription returns the name of the push subscription.
+func (m *Message) Subscription() string { return m.subscription }
+
+// DeliveryAttempt returns the number of delivery attempts.
+// It is 0 unless a dead-letter policy is set on the subscription.
+func (m *Message) DeliveryAttempt() int { return m.deliveryAttempt }
+
+// Ack responds to the push request with the ack status code.
+func (m *Message) Ack() { m.settle(true) }
+
+// Nack responds to the push request with the nack status code.
+func (m *Message) Nack() { m.settle(false) }
+
+func (m *Message) settle(ack bool) {
+	select {
+	case m.resultCh <- ack:
+	default:
+	}
+}
@@ -0,0 +1,74 @@
+package httppush
+
+import (
+	"context"
+	"net/http"
+)
+
+// DefaultMaxBodySize is the default maximum size of a request body.
+const DefaultMaxBodySize = 10 << 20
+
+// TokenVerifier verifies the bearer token attached to push requests,
+// e.g. the OIDC token signed by Google for an authenticated push subscription.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) error
+}
+
+// TokenVerifierFunc type is an adapter to allow the use of ordinary functions as TokenVerifier.
+type TokenVerifierFunc func(ctx context.Context, token string) error
+
+// Verify call f(ctx, token)
+func (f TokenVerifierFunc) Verify(ctx context.Context, token string) error {
+	return f(ctx, token)
+}
+
+// Config represents subscriber configuration.
+type Config struct {
+	TokenVerifier TokenVerifier
+	MaxBodySize   int64
+	AckStatus     int
+	NackStatus    int
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		MaxBodySize: DefaultMaxBodySize,
+		AckStatus:   http.StatusNoContent,
+		NackStatus:  http.StatusInternalServerError,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithTokenVerifier returns an Option that set TokenVerifier.
+// Requests without a valid bearer token are responded with 401 Unauthorized.
+func WithTokenVerifier(v TokenVerifier) Option {
+	return func(c *Config) {
+		c.TokenVerifier = v
+	}
+}
+
+// WithMaxBodySize returns an Option that set the maximum size of a request body.
+func WithMaxBodySize(n int64) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.MaxBodySize = n
+		}
+	}
+}
+
+// WithStatusCodes returns an Option that set the HTTP status codes responded for acked and nacked messages.
+// Pub/Sub regards 102, 200, 201, 202 and 204 as ack, and the others as nack.
+func WithStatusCodes(ack, nack int) Option {
+	return func(c *Config) {
+		c.AckStatus = ack
+		c.NackStatus = nack
+	}
+}
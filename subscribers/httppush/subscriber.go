@@ -0,0 +1,113 @@
+package httppush
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/wantedly/subee"
+)
+
+// Subscriber implements subee.Subscriber and http.Handler for Cloud Pub/Sub push subscriptions.
+// Each push request is fed into the subscribing Engine, and responded when the message is acked or nacked.
+type Subscriber struct {
+	*Config
+
+	// mu is read-locked while a push request is fed into f,
+	// so that Subscribe does not return until the messages in flight are handed to the Engine.
+	mu sync.RWMutex
+	f  func(subee.Message)
+}
+
+// NewSubscriber creates a new Subscriber instance.
+// The subscriber should be registered to a HTTP server as the push endpoint.
+func NewSubscriber(opts ...Option) *Subscriber {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	return &Subscriber{
+		Config: cfg,
+	}
+}
+
+// Subscribe implements subee.Subscriber.Subscribe.
+// Push requests are responded with 503 Service Unavailable while no Engine is subscribing.
+// It returns after ctx is done and the push requests being fed into f are handed over.
+func (s *Subscriber) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	s.mu.Lock()
+	s.f = f
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.f = nil
+	s.mu.Unlock()
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP.
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.TokenVerifier != nil {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || s.TokenVerifier.Verify(r.Context(), token) != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if !s.subscribing() {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	e := new(envelope)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.MaxBodySize)).Decode(e); err != nil {
+		http.Error(w, "invalid push request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m := newMessage(e)
+	if !s.feed(m) {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case ack := <-m.resultCh:
+		if ack {
+			w.WriteHeader(s.AckStatus)
+		} else {
+			w.WriteHeader(s.NackStatus)
+		}
+	case <-r.Context().Done():
+		// Pub/Sub regards the message as nacked when the request times out.
+	}
+}
+
+func (s *Subscriber) subscribing() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.f != nil
+}
+
+// feed passes m to the subscribing Engine, and returns false if no Engine is subscribing.
+func (s *Subscriber) feed(m *Message) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.f == nil {
+		return false
+	}
+	s.f(m)
+	return true
+}
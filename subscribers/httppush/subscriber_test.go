@@ -0,0 +1,217 @@
+package httppush_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/httppush"
+)
+
+const pushBody = `{
+	"message": {
+		"attributes": {"id": "aaabbbccc"},
+		"data": "Zm9v",
+		"messageId": "2070443601311540",
+		"publishTime": "2021-02-26T19:13:55.749Z"
+	},
+	"subscription": "projects/test-proj/subscriptions/test-sub",
+	"deliveryAttempt": 3
+}`
+
+func TestSubscriber(t *testing.T) {
+	sub := httppush.NewSubscriber()
+
+	var got *httppush.Message
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		got = msg.(*httppush.Message)
+		if string(msg.Data()) == "foo" {
+			return nil
+		}
+		return errors.New("error")
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	srv := httptest.NewServer(sub)
+	defer srv.Close()
+
+	resp := post(t, srv.URL, "", pushBody)
+	if got, want := resp.StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status code before subscribing is %d, want %d", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+	waitSubscribed(t, srv.URL)
+
+	resp = post(t, srv.URL, "", pushBody)
+	if got, want := resp.StatusCode, http.StatusNoContent; got != want {
+		t.Errorf("status code for acked message is %d, want %d", got, want)
+	}
+
+	type Msg struct {
+		Data            string
+		Meta            map[string]string
+		ID              string
+		PublishTime     time.Time
+		Subscription    string
+		DeliveryAttempt int
+	}
+	want := Msg{
+		Data:            "foo",
+		Meta:            map[string]string{"id": "aaabbbccc"},
+		ID:              "2070443601311540",
+		PublishTime:     time.Date(2021, 2, 26, 19, 13, 55, 749000000, time.UTC),
+		Subscription:    "projects/test-proj/subscriptions/test-sub",
+		DeliveryAttempt: 3,
+	}
+	if diff := cmp.Diff(want, Msg{
+		Data:            string(got.Data()),
+		Meta:            got.Metadata(),
+		ID:              got.MessageID(),
+		PublishTime:     got.PublishTime(),
+		Subscription:    got.Subscription(),
+		DeliveryAttempt: got.DeliveryAttempt(),
+	}); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+
+	resp = post(t, srv.URL, "", strings.Replace(pushBody, "Zm9v", "YmFy", 1))
+	if got, want := resp.StatusCode, http.StatusInternalServerError; got != want {
+		t.Errorf("status code for nacked message is %d, want %d", got, want)
+	}
+
+	resp = post(t, srv.URL, "", "{")
+	if got, want := resp.StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("status code for invalid request is %d, want %d", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+}
+
+func TestSubscriber_WithTokenVerifier(t *testing.T) {
+	sub := httppush.NewSubscriber(httppush.WithTokenVerifier(httppush.TokenVerifierFunc(func(ctx context.Context, token string) error {
+		if token != "valid" {
+			return errors.New("invalid token")
+		}
+		return nil
+	})))
+
+	srv := httptest.NewServer(sub)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sub.Subscribe(ctx, func(msg subee.Message) { msg.Ack() })
+	waitSubscribed(t, srv.URL)
+
+	for _, tc := range []struct {
+		auth   string
+		status int
+	}{
+		{auth: "", status: http.StatusUnauthorized},
+		{auth: "Bearer invalid", status: http.StatusUnauthorized},
+		{auth: "valid", status: http.StatusUnauthorized},
+		{auth: "Bearer valid", status: http.StatusNoContent},
+	} {
+		if got, want := post(t, srv.URL, tc.auth, pushBody).StatusCode, tc.status; got != want {
+			t.Errorf("status code with Authorization %q is %d, want %d", tc.auth, got, want)
+		}
+	}
+}
+
+func post(t *testing.T, url, auth, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create a request: %v", err)
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send a request: %v", err)
+	}
+	resp.Body.Close()
+
+	return resp
+}
+
+// waitSubscribed waits until the subscriber stops responding 503 Service Unavailable.
+func waitSubscribed(t *testing.T, url string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		resp, err := http.Post(url, "application/json", strings.NewReader("{"))
+		if err != nil {
+			t.Fatalf("failed to send a request: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			return
+		}
+	}
+	t.Fatal("subscriber did not start subscribing")
+}
+
+func TestSubscriber_WaitsForInFlightRequests(t *testing.T) {
+	sub := httppush.NewSubscriber()
+
+	srv := httptest.NewServer(sub)
+	defer srv.Close()
+
+	received := make(chan subee.Message)
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sub.Subscribe(ctx, func(msg subee.Message) {
+			if string(msg.Data()) != "foo" {
+				msg.Ack()
+				return
+			}
+			received <- msg
+			<-release
+		})
+	}()
+	waitSubscribed(t, srv.URL)
+
+	go func() {
+		if resp, err := http.Post(srv.URL, "application/json", strings.NewReader(pushBody)); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	msg := <-received
+
+	cancel()
+	select {
+	case <-errCh:
+		t.Fatal("Subscribe returned while a push request was being handed over")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	msg.Ack()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	if got, want := post(t, srv.URL, "", pushBody).StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status code after subscribing is %d, want %d", got, want)
+	}
+}
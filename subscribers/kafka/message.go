@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Message is wrapps *sarama.ConsumerMessage
+type Message struct {
+	*sarama.ConsumerMessage
+	metadata map[string]string
+	tracker  *offsetTracker
+}
+
+func newMessage(m *sarama.ConsumerMessage, t *offsetTracker) *Message {
+	metadata := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		if h != nil {
+			metadata[string(h.Key)] = string(h.Value)
+		}
+	}
+
+	return &Message{
+		ConsumerMessage: m,
+		metadata:        metadata,
+		tracker:         t,
+	}
+}
+
+// Data returns *sarama.ConsumerMessage.Value
+func (m *Message) Data() []byte { return m.ConsumerMessage.Value }
+
+// Metadata returns message headers.
+// When a header key appears more than once, the last value is used.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// MessageID returns the position of the message in the form of "<topic>/<partition>/<offset>".
+func (m *Message) MessageID() string {
+	return m.Topic + "/" + strconv.FormatInt(int64(m.Partition), 10) + "/" + strconv.FormatInt(m.Offset, 10)
+}
+
+// PublishTime returns the timestamp of the message.
+func (m *Message) PublishTime() time.Time { return m.Timestamp }
+
+// DeliveryAttempt returns 0 because Kafka does not count deliveries.
+func (m *Message) DeliveryAttempt() int { return 0 }
+
+// OrderingKey returns the message key, which determines the partition.
+func (m *Message) OrderingKey() string { return string(m.Key) }
+
+// Ack marks the message as consumed.
+// The offset is committed once all preceding messages in the partition are acked.
+func (m *Message) Ack() { m.tracker.ack(m.Offset) }
+
+// Nack marks the message as failed.
+// The offset is never committed in the current session, and the partition is redelivered from the message
+// after the session is restarted with the nack backoff, which rebalances the consumer group.
+func (m *Message) Nack() { m.tracker.nack(m.Offset) }
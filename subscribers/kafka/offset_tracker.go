@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+)
+
+type offsetState int
+
+const (
+	offsetPending offsetState = iota
+	offsetAcked
+	offsetNacked
+)
+
+// offsetTracker tracks messages delivered from a partition claim,
+// and marks the offset next to the contiguous acked messages.
+type offsetTracker struct {
+	mark   func(offset int64)
+	onNack func()
+
+	mu      sync.Mutex
+	offsets []int64
+	states  map[int64]offsetState
+	pending int
+	nacked  bool
+	closed  bool
+
+	settledCh chan struct{}
+}
+
+func newOffsetTracker(mark func(offset int64), onNack func()) *offsetTracker {
+	return &offsetTracker{
+		mark:      mark,
+		onNack:    onNack,
+		states:    make(map[int64]offsetState),
+		settledCh: make(chan struct{}, 1),
+	}
+}
+
+// add starts tracking the offset.
+// It returns false when the message should not be delivered because the partition has been nacked or revoked.
+func (t *offsetTracker) add(offset int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.nacked || t.closed {
+		return false
+	}
+
+	t.offsets = append(t.offsets, offset)
+	t.states[offset] = offsetPending
+	t.pending++
+
+	return true
+}
+
+func (t *offsetTracker) ack(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.settle(offset, offsetAcked) {
+		return
+	}
+
+	var n int
+	for n < len(t.offsets) && t.states[t.offsets[n]] == offsetAcked {
+		delete(t.states, t.offsets[n])
+		n++
+	}
+	if n > 0 {
+		t.mark(t.offsets[n-1] + 1)
+		t.offsets = t.offsets[n:]
+	}
+}
+
+func (t *offsetTracker) nack(offset int64) {
+	t.mu.Lock()
+	ok := t.settle(offset, offsetNacked)
+	first := ok && !t.nacked
+	if ok {
+		t.nacked = true
+	}
+	t.mu.Unlock()
+
+	if first {
+		t.onNack()
+	}
+}
+
+// settle must be called with t.mu held.
+func (t *offsetTracker) settle(offset int64, state offsetState) bool {
+	if t.closed {
+		return false
+	}
+	if s, ok := t.states[offset]; !ok || s != offsetPending {
+		return false
+	}
+
+	t.states[offset] = state
+	t.pending--
+
+	select {
+	case t.settledCh <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// drain waits for all tracked messages to be acked or nacked until the timeout elapses.
+// Messages settled after drain returns are ignored.
+func (t *offsetTracker) drain(timeout time.Duration) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+L:
+	for {
+		t.mu.Lock()
+		pending := t.pending
+		t.mu.Unlock()
+
+		if pending == 0 {
+			break
+		}
+
+		select {
+		case <-t.settledCh:
+		case <-timer.C:
+			break L
+		}
+	}
+
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+}
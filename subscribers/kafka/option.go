@@ -0,0 +1,79 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/wantedly/subee/internal/backoff"
+)
+
+const (
+	// DefaultDrainTimeout is the default duration to wait for in-flight messages on rebalance.
+	DefaultDrainTimeout = 30 * time.Second
+	// DefaultNackInitialBackoff is the default delay to restart the session after a message is nacked for the first time.
+	DefaultNackInitialBackoff = time.Second
+	// DefaultNackMaxBackoff is the default maximum delay to restart the session after messages are nacked.
+	DefaultNackMaxBackoff = time.Minute
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	SaramaConfig *sarama.Config
+	DrainTimeout time.Duration
+
+	NackInitialBackoff time.Duration
+	NackMaxBackoff     time.Duration
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		DrainTimeout:       DefaultDrainTimeout,
+		NackInitialBackoff: DefaultNackInitialBackoff,
+		NackMaxBackoff:     DefaultNackMaxBackoff,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithSaramaConfig returns an Option that set *sarama.Config to create the consumer group.
+// sarama.NewConfig() is used by default.
+func WithSaramaConfig(cfg *sarama.Config) Option {
+	return func(c *Config) {
+		c.SaramaConfig = cfg
+	}
+}
+
+// WithDrainTimeout returns an Option that set the duration to wait for in-flight messages to be acked or nacked
+// when partitions are revoked on rebalance or shutdown.
+// It should be shorter than sarama.Config.Consumer.Group.Rebalance.Timeout.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.DrainTimeout = d
+		}
+	}
+}
+
+// WithNackBackoff returns an Option that set the delay to restart the session after a message is nacked.
+// It starts with initial and doubles on each consecutive restart caused by nacks, up to max.
+// The backoff is not capped if max is not positive.
+func WithNackBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		if initial >= 0 {
+			c.NackInitialBackoff = initial
+			c.NackMaxBackoff = max
+		}
+	}
+}
+
+// nackBackoff returns the delay to restart the session after restarts consecutive restarts caused by nacks.
+func (c *Config) nackBackoff(restarts int) time.Duration {
+	return backoff.Exponential(c.NackInitialBackoff, c.NackMaxBackoff, restarts)
+}
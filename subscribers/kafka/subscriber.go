@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+type subscriberImpl struct {
+	*Config
+	group     sarama.ConsumerGroup
+	topics    []string
+	ownsGroup bool
+}
+
+// CreateSubscriber returns Subscriber implementation that joins the consumer group and consumes the topics.
+// The consumer group is closed on Close.
+func CreateSubscriber(brokers []string, groupID string, topics []string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if len(brokers) == 0 {
+		return nil, errors.New("missing kafka brokers")
+	}
+	if len(groupID) == 0 {
+		return nil, errors.New("missing kafka consumer group id")
+	}
+	if len(topics) == 0 {
+		return nil, errors.New("missing kafka topics")
+	}
+
+	saramaCfg := cfg.SaramaConfig
+	if saramaCfg == nil {
+		saramaCfg = sarama.NewConfig()
+	}
+
+	group, err := sarama.NewConsumerGroup(brokers, groupID, saramaCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kafka consumer group")
+	}
+
+	return &subscriberImpl{
+		Config:    cfg,
+		group:     group,
+		topics:    topics,
+		ownsGroup: true,
+	}, nil
+}
+
+// NewSubscriber returns Subscriber implementation that consumes the topics with the existing consumer group.
+// The consumer group is not closed by the subscriber.
+func NewSubscriber(group sarama.ConsumerGroup, topics []string, opts ...Option) subee.Subscriber {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	return &subscriberImpl{
+		Config: cfg,
+		group:  group,
+		topics: topics,
+	}
+}
+
+// Subscribe consumes the topics in consumer group sessions.
+//
+// Kafka redelivers messages only from the committed offset, so a nacked message is redelivered by restarting the session.
+// The partition of the nacked message stops delivering messages, and the session is restarted after the nack backoff.
+// Other partitions keep being consumed during the backoff, but restarting the session rebalances the whole consumer group,
+// so consumers should return subee.Permanent errors for messages that never succeed, or dead-letter them.
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	var restarts int
+
+	for {
+		sessCtx, cancel := context.WithCancel(ctx)
+		h := &consumerGroupHandler{
+			Config:  s.Config,
+			f:       f,
+			backoff: s.nackBackoff(restarts),
+			restart: cancel,
+		}
+		err := s.group.Consume(sessCtx, s.topics, h)
+		h.stopRestart()
+		cancel()
+
+		switch {
+		case err == sarama.ErrClosedConsumerGroup:
+			return nil
+		case err != nil:
+			return errors.Wrap(err, "failed to consume kafka topics")
+		case ctx.Err() != nil:
+			return nil
+		}
+
+		if h.nacked() {
+			restarts++
+		} else {
+			restarts = 0
+		}
+	}
+}
+
+// Close closes the consumer group created by CreateSubscriber.
+func (s *subscriberImpl) Close() error {
+	if !s.ownsGroup {
+		return nil
+	}
+	return errors.WithStack(s.group.Close())
+}
+
+// consumerGroupHandler implements sarama.ConsumerGroupHandler for a consumer group session.
+type consumerGroupHandler struct {
+	*Config
+	f       func(subee.Message)
+	backoff time.Duration
+	restart context.CancelFunc
+
+	mu           sync.Mutex
+	restartTimer *time.Timer
+}
+
+// scheduleRestart restarts the session after the backoff to redeliver nacked messages.
+func (h *consumerGroupHandler) scheduleRestart() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.restartTimer == nil {
+		h.restartTimer = time.AfterFunc(h.backoff, h.restart)
+	}
+}
+
+func (h *consumerGroupHandler) stopRestart() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.restartTimer != nil {
+		h.restartTimer.Stop()
+	}
+}
+
+func (h *consumerGroupHandler) nacked() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.restartTimer != nil
+}
+
+func (h *consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *consumerGroupHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	sess.Commit()
+	return nil
+}
+
+// ConsumeClaim delivers messages from the partition until it is revoked,
+// and waits for in-flight messages so that their offsets are marked before the rebalance.
+func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	t := newOffsetTracker(func(offset int64) {
+		sess.MarkOffset(claim.Topic(), claim.Partition(), offset, "")
+	}, h.scheduleRestart)
+	defer t.drain(h.DrainTimeout)
+
+	for m := range claim.Messages() {
+		if t.add(m.Offset) {
+			h.f(newMessage(m, t))
+		}
+	}
+
+	return nil
+}
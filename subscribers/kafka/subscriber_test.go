@@ -0,0 +1,410 @@
+package kafka_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/kafka"
+)
+
+func TestSubscriber(t *testing.T) {
+	broker := newFakeBroker()
+
+	type Msg struct {
+		Data []byte
+		Meta map[string]string
+	}
+
+	in := []Msg{
+		{Data: []byte("foo"), Meta: map[string]string{}},
+		{Data: []byte("bar"), Meta: map[string]string{"corge": "12", "id": "aaabbbccc"}},
+		{Data: []byte("baz"), Meta: map[string]string{}},
+		{Data: []byte("qux"), Meta: map[string]string{}},
+	}
+	for i, m := range in {
+		broker.Produce("test-topic", int32(i%2), m.Data, m.Meta)
+	}
+
+	subscriber := kafka.NewSubscriber(broker.ConsumerGroup(), []string{"test-topic"})
+
+	msgCh := make(chan subee.Message, len(in))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) {
+			msg.Ack()
+			msgCh <- msg
+		})
+	}()
+
+	out := []Msg{}
+	for range in {
+		m := <-msgCh
+		out = append(out, Msg{Data: m.Data(), Meta: m.Metadata()})
+	}
+
+	sorter := cmp.Transformer("Sort", func(in []Msg) []Msg {
+		out := append([]Msg(nil), in...)
+		sort.Slice(out, func(i, j int) bool { return string(out[i].Data) < string(out[j].Data) })
+		return out
+	})
+	if diff := cmp.Diff(in, out, sorter); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	for _, p := range []int32{0, 1} {
+		if got, want := broker.Committed("test-topic", p), int64(2); got != want {
+			t.Errorf("committed offset of partition %d is %d, want %d", p, got, want)
+		}
+	}
+}
+
+func TestSubscriber_CommitContiguousOffsets(t *testing.T) {
+	broker := newFakeBroker()
+	for _, v := range []string{"foo", "bar", "baz"} {
+		broker.Produce("test-topic", 0, []byte(v), nil)
+	}
+
+	subscriber := kafka.NewSubscriber(broker.ConsumerGroup(), []string{"test-topic"})
+
+	msgCh := make(chan subee.Message, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) { msgCh <- msg })
+	}()
+
+	msgs := []subee.Message{<-msgCh, <-msgCh, <-msgCh}
+
+	msgs[0].Ack()
+	msgs[2].Ack()
+	if got, want := broker.Committed("test-topic", 0), int64(1); got != want {
+		t.Errorf("committed offset is %d, want %d", got, want)
+	}
+
+	msgs[1].Ack()
+	if got, want := broker.Committed("test-topic", 0), int64(3); got != want {
+		t.Errorf("committed offset is %d, want %d", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+}
+
+func TestSubscriber_Nack(t *testing.T) {
+	broker := newFakeBroker()
+	for _, v := range []string{"foo", "bar", "baz"} {
+		broker.Produce("test-topic", 0, []byte(v), nil)
+	}
+
+	subscriber := kafka.NewSubscriber(broker.ConsumerGroup(), []string{"test-topic"}, kafka.WithNackBackoff(10*time.Millisecond, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		received []string
+		nacked   bool
+	)
+	done := make(chan struct{})
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			received = append(received, string(msg.Data()))
+			if string(msg.Data()) == "bar" && !nacked {
+				nacked = true
+				msg.Nack()
+				return
+			}
+			msg.Ack()
+			if string(msg.Data()) == "baz" {
+				close(done)
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("nacked message was not redelivered")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"foo", "bar", "bar", "baz"}, received); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+	if got, want := broker.Committed("test-topic", 0), int64(3); got != want {
+		t.Errorf("committed offset is %d, want %d", got, want)
+	}
+}
+
+func TestSubscriber_Rebalance(t *testing.T) {
+	broker := newFakeBroker()
+	broker.Produce("test-topic", 0, []byte("foo"), nil)
+
+	group := broker.ConsumerGroup()
+	subscriber := kafka.NewSubscriber(group, []string{"test-topic"}, kafka.WithDrainTimeout(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgCh := make(chan subee.Message, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) { msgCh <- msg })
+	}()
+
+	msg := <-msgCh
+	group.Rebalance()
+
+	select {
+	case <-group.cleanedUp:
+		t.Fatal("session was cleaned up before in-flight messages were settled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	msg.Ack()
+
+	select {
+	case <-group.cleanedUp:
+	case <-time.After(time.Second):
+		t.Fatal("session was not cleaned up after in-flight messages were settled")
+	}
+	if got, want := broker.Committed("test-topic", 0), int64(1); got != want {
+		t.Errorf("committed offset is %d, want %d", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+}
+
+// fakeBroker is an in-process Kafka broker that holds partition logs and committed offsets of a consumer group.
+type fakeBroker struct {
+	mu        sync.Mutex
+	logs      map[string][][]*sarama.ConsumerMessage
+	committed map[string]map[int32]int64
+	updateCh  chan struct{}
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		logs:      make(map[string][][]*sarama.ConsumerMessage),
+		committed: make(map[string]map[int32]int64),
+		updateCh:  make(chan struct{}),
+	}
+}
+
+func (b *fakeBroker) Produce(topic string, partition int32, value []byte, headers map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for int32(len(b.logs[topic])) <= partition {
+		b.logs[topic] = append(b.logs[topic], nil)
+	}
+
+	m := &sarama.ConsumerMessage{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    int64(len(b.logs[topic][partition])),
+		Value:     value,
+	}
+	for k, v := range headers {
+		m.Headers = append(m.Headers, &sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	b.logs[topic][partition] = append(b.logs[topic][partition], m)
+
+	close(b.updateCh)
+	b.updateCh = make(chan struct{})
+}
+
+func (b *fakeBroker) Committed(topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[topic][partition]
+}
+
+func (b *fakeBroker) commit(topic string, partition int32, offset int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.committed[topic] == nil {
+		b.committed[topic] = make(map[int32]int64)
+	}
+	b.committed[topic][partition] = offset
+}
+
+func (b *fakeBroker) fetch(topic string, partition int32, offset int64) (*sarama.ConsumerMessage, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if log := b.logs[topic][partition]; offset < int64(len(log)) {
+		return log[offset], nil
+	}
+	return nil, b.updateCh
+}
+
+func (b *fakeBroker) partitions(topic string) []int32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ps := make([]int32, len(b.logs[topic]))
+	for i := range ps {
+		ps[i] = int32(i)
+	}
+	return ps
+}
+
+func (b *fakeBroker) ConsumerGroup() *fakeConsumerGroup {
+	return &fakeConsumerGroup{
+		broker:    b,
+		closed:    make(chan struct{}),
+		cleanedUp: make(chan struct{}, 16),
+	}
+}
+
+// fakeConsumerGroup implements sarama.ConsumerGroup as the only member of the group.
+type fakeConsumerGroup struct {
+	sarama.ConsumerGroup
+	broker *fakeBroker
+
+	mu        sync.Mutex
+	rebalance context.CancelFunc
+	closeOnce sync.Once
+	closed    chan struct{}
+	cleanedUp chan struct{}
+}
+
+func (g *fakeConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	select {
+	case <-g.closed:
+		return sarama.ErrClosedConsumerGroup
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g.mu.Lock()
+	g.rebalance = cancel
+	g.mu.Unlock()
+
+	sess := &fakeSession{ctx: ctx, broker: g.broker}
+	if err := handler.Setup(sess); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		for _, partition := range g.broker.partitions(topic) {
+			claim := &fakeClaim{
+				topic:     topic,
+				partition: partition,
+				msgCh:     make(chan *sarama.ConsumerMessage),
+			}
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				claim.feed(ctx, g.broker)
+			}()
+			go func() {
+				defer wg.Done()
+				handler.ConsumeClaim(sess, claim)
+			}()
+		}
+	}
+	wg.Wait()
+
+	err := handler.Cleanup(sess)
+	g.cleanedUp <- struct{}{}
+	return err
+}
+
+func (g *fakeConsumerGroup) Rebalance() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.rebalance != nil {
+		g.rebalance()
+	}
+}
+
+func (g *fakeConsumerGroup) Close() error {
+	g.closeOnce.Do(func() { close(g.closed) })
+	return nil
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	broker *fakeBroker
+}
+
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.broker.commit(topic, partition, offset)
+}
+
+func (s *fakeSession) Commit() {}
+
+func (s *fakeSession) Context() context.Context { return s.ctx }
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+	topic     string
+	partition int32
+	msgCh     chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string { return c.topic }
+
+func (c *fakeClaim) Partition() int32 { return c.partition }
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgCh }
+
+// feed sends messages from the committed offset until ctx is done.
+func (c *fakeClaim) feed(ctx context.Context, b *fakeBroker) {
+	defer close(c.msgCh)
+
+	for offset := b.Committed(c.topic, c.partition); ; {
+		m, updateCh := b.fetch(c.topic, c.partition, offset)
+		if m == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-updateCh:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case c.msgCh <- m:
+			offset++
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Broker is an in-process message broker that holds named topics.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*Topic
+}
+
+// NewBroker creates a new Broker instance.
+func NewBroker() *Broker {
+	return &Broker{
+		topics: make(map[string]*Topic),
+	}
+}
+
+// Topic returns the topic with the name, creating it if it does not exist.
+func (b *Broker) Topic(name string) *Topic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		t = &Topic{
+			name:          name,
+			subscriptions: make(map[string]*Subscription),
+		}
+		b.topics[name] = t
+	}
+
+	return t
+}
+
+// Topic is a named topic that delivers published messages to all its subscriptions.
+type Topic struct {
+	name          string
+	seq           uint64
+	mu            sync.RWMutex
+	subscriptions map[string]*Subscription
+}
+
+// Name returns the name of the topic.
+func (t *Topic) Name() string { return t.name }
+
+// Subscription returns the subscription with the name, creating it with opts if it does not exist.
+// A subscription receives messages published after it is created.
+func (t *Topic) Subscription(name string, opts ...Option) *Subscription {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.subscriptions[name]
+	if !ok {
+		cfg := newDefaultConfig()
+		cfg.apply(opts)
+
+		s = &Subscription{
+			Config:  cfg,
+			name:    name,
+			readyCh: make(chan struct{}, 1),
+		}
+		t.subscriptions[name] = s
+	}
+
+	return s
+}
+
+// Publish publishes a message to all subscriptions of the topic and returns the message ID.
+// data and metadata are copied, so they can be modified after Publish returns.
+func (t *Topic) Publish(ctx context.Context, data []byte, metadata map[string]string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	id := strconv.FormatUint(atomic.AddUint64(&t.seq, 1), 10)
+	publishTime := time.Now()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, s := range t.subscriptions {
+		md := make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			md[k] = v
+		}
+		s.push(&entry{
+			id:          id,
+			data:        append([]byte(nil), data...),
+			metadata:    md,
+			publishTime: publishTime,
+		})
+	}
+
+	return id, nil
+}
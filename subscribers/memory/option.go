@@ -0,0 +1,44 @@
+package memory
+
+import "time"
+
+// DefaultAckDeadline is the default duration after which unacked messages are redelivered.
+const DefaultAckDeadline = 10 * time.Second
+
+// Config represents subscription configuration.
+type Config struct {
+	AckDeadline time.Duration
+	NackDelay   time.Duration
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		AckDeadline: DefaultAckDeadline,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscription Option
+type Option func(*Config)
+
+// WithAckDeadline returns an Option that set the duration after which messages neither acked nor nacked are redelivered.
+// Messages are never redelivered by the deadline when it is not positive.
+func WithAckDeadline(d time.Duration) Option {
+	return func(c *Config) {
+		c.AckDeadline = d
+	}
+}
+
+// WithNackDelay returns an Option that set the delay before nacked messages are redelivered.
+func WithNackDelay(d time.Duration) Option {
+	return func(c *Config) {
+		if d >= 0 {
+			c.NackDelay = d
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wantedly/subee"
+)
+
+// entry is a message held by a subscription.
+type entry struct {
+	id          string
+	data        []byte
+	metadata    map[string]string
+	publishTime time.Time
+	attempts    int
+}
+
+// Subscription implements subee.Subscriber.
+// Messages are delivered at least once, and concurrent Subscribe calls share them.
+type Subscription struct {
+	*Config
+	name string
+
+	mu      sync.Mutex
+	ready   []*entry
+	readyCh chan struct{}
+}
+
+// Name returns the name of the subscription.
+func (s *Subscription) Name() string { return s.name }
+
+func (s *Subscription) push(e *entry) {
+	s.mu.Lock()
+	s.ready = append(s.ready, e)
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+func (s *Subscription) pop() (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.ready) == 0 {
+		return nil, false
+	}
+
+	e := s.ready[0]
+	s.ready = s.ready[1:]
+	e.attempts++
+	if len(s.ready) > 0 {
+		s.notify()
+	}
+
+	return e, true
+}
+
+func (s *Subscription) notify() {
+	select {
+	case s.readyCh <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe implements subee.Subscriber.Subscribe.
+func (s *Subscription) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	for ctx.Err() == nil {
+		e, ok := s.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-s.readyCh:
+				continue
+			}
+		}
+
+		f(s.deliver(e))
+	}
+
+	return nil
+}
+
+// deliver creates a message for a delivery of the entry.
+// The entry is redelivered when the message is not settled until the ack deadline.
+func (s *Subscription) deliver(e *entry) *Message {
+	s.mu.Lock()
+	m := &Message{entry: e, sub: s, attempt: e.attempts}
+	s.mu.Unlock()
+
+	if s.AckDeadline > 0 {
+		m.mu.Lock()
+		m.timer = time.AfterFunc(s.AckDeadline, func() {
+			if m.settle() {
+				s.push(e)
+			}
+		})
+		m.mu.Unlock()
+	}
+
+	return m
+}
+
+// Message is a message delivered from Subscription.
+type Message struct {
+	*entry
+	sub     *Subscription
+	attempt int
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	settled bool
+}
+
+// MessageID returns the ID assigned by Topic.Publish.
+func (m *Message) MessageID() string { return m.id }
+
+// PublishTime returns the time when the message was published.
+func (m *Message) PublishTime() time.Time { return m.publishTime }
+
+// DeliveryAttempt returns the number of times the message has been delivered, starting from 1.
+func (m *Message) DeliveryAttempt() int { return m.attempt }
+
+// OrderingKey returns an empty string because Topic does not order messages.
+func (m *Message) OrderingKey() string { return "" }
+
+// Data returns the message payload.
+func (m *Message) Data() []byte { return m.data }
+
+// Metadata returns the message metadata.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// Ack removes the message from the subscription.
+// It is ignored when the message has already been redelivered by the ack deadline.
+func (m *Message) Ack() {
+	m.settle()
+}
+
+// Nack redelivers the message after the nack delay.
+// It is ignored when the message has already been redelivered by the ack deadline.
+func (m *Message) Nack() {
+	if !m.settle() {
+		return
+	}
+
+	if m.sub.NackDelay > 0 {
+		time.AfterFunc(m.sub.NackDelay, func() { m.sub.push(m.entry) })
+		return
+	}
+	m.sub.push(m.entry)
+}
+
+func (m *Message) settle() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.settled {
+		return false
+	}
+	m.settled = true
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+
+	return true
+}
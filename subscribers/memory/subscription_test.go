@@ -0,0 +1,147 @@
+package memory_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/memory"
+)
+
+func TestTopic_Publish(t *testing.T) {
+	ctx := context.Background()
+	topic := memory.NewBroker().Topic("test-topic")
+	foo := topic.Subscription("foo")
+	bar := topic.Subscription("bar")
+
+	data := []byte("baz")
+	if _, err := topic.Publish(ctx, data, map[string]string{"id": "qux"}); err != nil {
+		t.Fatalf("Publish returned an error: %v", err)
+	}
+	data[0] = 'x'
+
+	for _, s := range []*memory.Subscription{foo, bar} {
+		msg := receive(t, s)
+		if got, want := string(msg.Data()), "baz"; got != want {
+			t.Errorf("Message.Data() from %s is %q, want %q", s.Name(), got, want)
+		}
+		if diff := cmp.Diff(map[string]string{"id": "qux"}, msg.Metadata()); diff != "" {
+			t.Errorf("Message.Metadata() from %s differs: (-want +got)\n%s", s.Name(), diff)
+		}
+		msg.Ack()
+	}
+}
+
+func TestSubscription_Nack(t *testing.T) {
+	ctx := context.Background()
+	topic := memory.NewBroker().Topic("test-topic")
+	sub := topic.Subscription("test-sub", memory.WithNackDelay(20*time.Millisecond))
+
+	topic.Publish(ctx, []byte("foo"), nil)
+
+	msg := receive(t, sub)
+	begin := time.Now()
+	msg.Nack()
+
+	msg = receive(t, sub)
+	if got, want := time.Since(begin), 20*time.Millisecond; got < want {
+		t.Errorf("message was redelivered after %v, want %v", got, want)
+	}
+	if got, want := msg.(*memory.Message).DeliveryAttempt(), 2; got != want {
+		t.Errorf("Message.DeliveryAttempt() is %d, want %d", got, want)
+	}
+	msg.Ack()
+}
+
+func TestSubscription_AckDeadline(t *testing.T) {
+	ctx := context.Background()
+	topic := memory.NewBroker().Topic("test-topic")
+	sub := topic.Subscription("test-sub", memory.WithAckDeadline(10*time.Millisecond))
+
+	topic.Publish(ctx, []byte("foo"), nil)
+
+	expired := receive(t, sub)
+	msg := receive(t, sub)
+	if got, want := msg.(*memory.Message).DeliveryAttempt(), 2; got != want {
+		t.Errorf("Message.DeliveryAttempt() is %d, want %d", got, want)
+	}
+
+	// Nacking the expired delivery does not redeliver the message again.
+	expired.Nack()
+	msg.Ack()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	sub.Subscribe(ctx, func(msg subee.Message) {
+		t.Errorf("message %q was redelivered after ack", msg.Data())
+	})
+}
+
+func TestSubscription_Subscribe_Concurrently(t *testing.T) {
+	ctx := context.Background()
+	topic := memory.NewBroker().Topic("test-topic")
+	sub := topic.Subscription("test-sub")
+
+	var want []string
+	for _, v := range []string{"foo", "bar", "baz", "qux", "quux"} {
+		id, err := topic.Publish(ctx, []byte(v), nil)
+		if err != nil {
+			t.Fatalf("Publish returned an error: %v", err)
+		}
+		want = append(want, id)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu  sync.Mutex
+		got []string
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sub.Subscribe(ctx, func(msg subee.Message) {
+				msg.Ack()
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, msg.(*memory.Message).MessageID())
+				if len(got) == len(want) {
+					cancel()
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	sort.Strings(got)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Received messages differ: (-want +got)\n%s", diff)
+	}
+}
+
+func receive(t *testing.T, s *memory.Subscription) subee.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var msg subee.Message
+	s.Subscribe(ctx, func(m subee.Message) {
+		if msg == nil {
+			msg = m
+			cancel()
+		}
+	})
+	if msg == nil {
+		t.Fatalf("no messages were delivered from %s", s.Name())
+	}
+	return msg
+}
@@ -0,0 +1,19 @@
+module github.com/wantedly/subee/subscribers/mqtt
+
+go 1.24.0
+
+require (
+	github.com/eclipse/paho.golang v0.23.0
+	github.com/google/go-cmp v0.7.0
+	github.com/mochi-mqtt/server/v2 v2.7.9
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/rs/xid v1.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/wantedly/subee => ../..
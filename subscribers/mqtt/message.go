@@ -0,0 +1,75 @@
+package mqtt
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const (
+	// MetadataTopic is the metadata key of the topic name the message was published to.
+	MetadataTopic = "mqtt_topic"
+	// MetadataQoS is the metadata key of the QoS the message was delivered with.
+	MetadataQoS = "mqtt_qos"
+	// MetadataRetained is the metadata key that is "true" if the message was retained.
+	MetadataRetained = "mqtt_retained"
+)
+
+// Message is wrapps *paho.Publish
+type Message struct {
+	*paho.Publish
+	metadata   map[string]string
+	conn       *connection
+	settleOnce sync.Once
+}
+
+func newMessage(p *paho.Publish, c *connection) *Message {
+	var props paho.UserProperties
+	if p.Properties != nil {
+		props = p.Properties.User
+	}
+
+	metadata := make(map[string]string, len(props)+3)
+	for _, prop := range props {
+		metadata[prop.Key] = prop.Value
+	}
+	metadata[MetadataTopic] = p.Topic
+	metadata[MetadataQoS] = strconv.Itoa(int(p.QoS))
+	metadata[MetadataRetained] = strconv.FormatBool(p.Retain)
+
+	return &Message{
+		Publish:  p,
+		metadata: metadata,
+		conn:     c,
+	}
+}
+
+// Data returns the message payload.
+func (m *Message) Data() []byte { return m.Payload }
+
+// Metadata returns the user properties of the message with the topic name, the QoS and the retained flag.
+// When a user property key appears more than once, the last value is used.
+// User properties named as the MetadataTopic, MetadataQoS and MetadataRetained keys are overwritten.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// Ack acknowledges the message to the broker with PUBACK for QoS 1 or PUBREC for QoS 2.
+// Acknowledgements are sent in the order messages were received, as MQTT requires.
+func (m *Message) Ack() {
+	m.settleOnce.Do(func() {
+		m.conn.ack(m.Publish)
+	})
+}
+
+// Nack leaves the message unacknowledged to have it redelivered.
+// MQTT has no negative acknowledgements, and acknowledgements must be sent in the order messages were received,
+// so an unacknowledged QoS 1 or 2 message holds back the acknowledgements of all the messages received after it.
+// Therefore the subscriber stops receiving messages on the connection, and reconnects after the reconnect backoff
+// to resume the session, in which the broker redelivers every unacknowledged message.
+// Messages acked after the nacked one on the same connection may be redelivered as well.
+// QoS 0 messages are never redelivered.
+func (m *Message) Nack() {
+	m.settleOnce.Do(func() {
+		m.conn.nack(m.Publish)
+	})
+}
@@ -0,0 +1,91 @@
+package mqtt
+
+import (
+	"time"
+
+	"github.com/wantedly/subee/internal/backoff"
+)
+
+const (
+	// DefaultQoS is the default QoS of subscriptions.
+	DefaultQoS = 1
+	// DefaultDrainTimeout is the default duration to wait for messages to be settled before disconnecting.
+	DefaultDrainTimeout = 30 * time.Second
+	// DefaultReconnectInitialBackoff is the default delay to reconnect for the first time.
+	DefaultReconnectInitialBackoff = time.Second
+	// DefaultReconnectMaxBackoff is the default maximum delay to reconnect.
+	DefaultReconnectMaxBackoff = time.Minute
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	QoS          byte
+	SharedGroup  string
+	DrainTimeout time.Duration
+
+	ReconnectInitialBackoff time.Duration
+	ReconnectMaxBackoff     time.Duration
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		QoS:                     DefaultQoS,
+		DrainTimeout:            DefaultDrainTimeout,
+		ReconnectInitialBackoff: DefaultReconnectInitialBackoff,
+		ReconnectMaxBackoff:     DefaultReconnectMaxBackoff,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithQoS returns an Option that set the QoS of subscriptions.
+// Messages received with QoS 1 and 2 are acknowledged to the broker on Ack.
+func WithQoS(qos byte) Option {
+	return func(c *Config) {
+		if qos <= 2 {
+			c.QoS = qos
+		}
+	}
+}
+
+// WithSharedGroup returns an Option that subscribes the topic filters as shared subscriptions of the group.
+// Messages are distributed among the subscribers in the group, e.g. "$share/<group>/<filter>".
+func WithSharedGroup(group string) Option {
+	return func(c *Config) {
+		c.SharedGroup = group
+	}
+}
+
+// WithDrainTimeout returns an Option that set the duration to wait for messages to be settled
+// before disconnecting on Close or on reconnection.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		if d >= 0 {
+			c.DrainTimeout = d
+		}
+	}
+}
+
+// WithReconnectBackoff returns an Option that set the delay to reconnect after a message is nacked or the connection is lost.
+// It starts with initial and doubles on each consecutive reconnection until a message is acked, up to max.
+// The backoff is not capped if max is not positive.
+func WithReconnectBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		if initial >= 0 {
+			c.ReconnectInitialBackoff = initial
+			c.ReconnectMaxBackoff = max
+		}
+	}
+}
+
+// reconnectBackoff returns the delay to reconnect after restarts consecutive reconnections.
+func (c *Config) reconnectBackoff(restarts int) time.Duration {
+	return backoff.Exponential(c.ReconnectInitialBackoff, c.ReconnectMaxBackoff, restarts)
+}
@@ -0,0 +1,382 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/eclipse/paho.golang/paho/session"
+	"github.com/eclipse/paho.golang/paho/session/state"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// ackInterval is the interval to send acknowledgements, which are buffered to be sent in order.
+const ackInterval = 50 * time.Millisecond
+
+// DialFunc opens a network connection to the broker, e.g. with net.Dialer.DialContext.
+// The connection must be safe for concurrent writes.
+type DialFunc func(ctx context.Context) (net.Conn, error)
+
+type subscriberImpl struct {
+	*Config
+	dial    DialFunc
+	connect *paho.Connect
+	filters []string
+	session *state.State
+
+	mu   sync.Mutex
+	conn *connection
+}
+
+// CreateSubscriber returns Subscriber implementation that connects to the MQTT 5 broker with the connection opened by dial,
+// and subscribes the topic filters.
+// Set the client ID and a positive session expiry interval to the connect packet, so that unacknowledged messages
+// are redelivered when the session is resumed.
+// The subscriber reconnects with CleanStart disabled after a message is nacked or the connection is lost,
+// and keeps retrying with the reconnect backoff while the broker is unavailable.
+// Subscriptions are kept in the session on Close, and the client is disconnected
+// after the received messages are settled or DrainTimeout elapses.
+func CreateSubscriber(dial DialFunc, connect *paho.Connect, filters []string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if dial == nil {
+		return nil, errors.New("missing mqtt dial function")
+	}
+	if connect == nil {
+		return nil, errors.New("missing mqtt connect packet")
+	}
+	if len(filters) == 0 {
+		return nil, errors.New("missing mqtt topic filters")
+	}
+
+	if cfg.SharedGroup != "" {
+		shared := make([]string, len(filters))
+		for i, f := range filters {
+			shared[i] = "$share/" + cfg.SharedGroup + "/" + f
+		}
+		filters = shared
+	}
+
+	return &subscriberImpl{
+		Config:  cfg,
+		dial:    dial,
+		connect: connect,
+		filters: filters,
+		session: state.NewInMemory(),
+	}, nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	var (
+		connected bool
+		restarts  int
+	)
+	for {
+		c, err := s.open(ctx, f, connected)
+		if ctx.Err() != nil {
+			// Received messages are acknowledged on the connection, so it is kept until Close.
+			if c != nil {
+				c.stopReceiving()
+			}
+			return nil
+		}
+		if err != nil && !connected {
+			return errors.WithStack(err)
+		}
+		connected = true
+
+		if err == nil {
+			select {
+			case <-ctx.Done():
+				c.stopReceiving()
+				return nil
+			case <-c.nackedCh:
+			case <-c.client.Done():
+			}
+			c.stopReceiving()
+
+			if c.hasAcked() {
+				restarts = 0
+			}
+		}
+		timer := time.NewTimer(s.reconnectBackoff(restarts))
+		restarts++
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		// The network connection is closed even if it fails, and the session is resumed on the next connection.
+		if c != nil {
+			s.disconnect(c)
+		}
+	}
+}
+
+// Close disconnects from the broker after the received messages are settled or DrainTimeout elapses.
+func (s *subscriberImpl) Close() error {
+	s.mu.Lock()
+	c := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+
+	var err error
+	if c != nil {
+		c.stopReceiving()
+		err = s.disconnect(c)
+	}
+
+	if cerr := s.session.Close(); err == nil {
+		err = errors.Wrap(cerr, "failed to close mqtt session")
+	}
+
+	return errors.WithStack(err)
+}
+
+// open connects to the broker and subscribes the topic filters.
+// Unacknowledged messages of the resumed session may be received before the subscriptions are made.
+// The connection is returned with the error and kept for Close even if the subscriptions fail.
+func (s *subscriberImpl) open(ctx context.Context, f func(subee.Message), reconnect bool) (*connection, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial mqtt broker")
+	}
+
+	c := &connection{
+		nackedCh: make(chan struct{}),
+		acks:     make(map[*paho.Publish]bool),
+		unsent:   make(map[uint16]struct{}),
+		sentCh:   make(chan struct{}, 1),
+	}
+	c.client = paho.NewClient(paho.ClientConfig{
+		Conn:                       conn,
+		Session:                    &ackSession{SessionManager: s.session, conn: c},
+		EnableManualAcknowledgment: true,
+		SendAcksInterval:           ackInterval,
+		OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+			func(r paho.PublishReceived) (bool, error) {
+				c.receive(r.Packet, f)
+				return true, nil
+			},
+		},
+	})
+
+	cp := *s.connect
+	if reconnect {
+		// The session is resumed to have unacknowledged messages redelivered.
+		cp.CleanStart = false
+	}
+	if _, err := c.client.Connect(ctx, &cp); err != nil {
+		return nil, errors.Wrap(err, "failed to connect to mqtt broker")
+	}
+
+	// Messages may be received from now on, so the connection is disconnected by Close after they are settled.
+	s.mu.Lock()
+	s.conn = c
+	s.mu.Unlock()
+
+	subs := make([]paho.SubscribeOptions, len(s.filters))
+	for i, filter := range s.filters {
+		// Retained messages are sent only when the subscription is created, not on every reconnection.
+		subs[i] = paho.SubscribeOptions{Topic: filter, QoS: s.QoS, RetainHandling: 1}
+	}
+	if suback, err := c.client.Subscribe(ctx, &paho.Subscribe{Subscriptions: subs}); err != nil {
+		c.stopReceiving()
+		if suback != nil {
+			for i, code := range suback.Reasons {
+				if code >= 0x80 && i < len(s.filters) {
+					return c, errors.Errorf("mqtt broker rejected the subscription to %q with reason code 0x%02x", s.filters[i], code)
+				}
+			}
+		}
+		return c, errors.Wrap(err, "failed to subscribe mqtt topics")
+	}
+
+	return c, nil
+}
+
+// disconnect disconnects from the broker after the messages received on the connection are settled
+// and their acknowledgements are sent, or DrainTimeout elapses.
+func (s *subscriberImpl) disconnect(c *connection) error {
+	c.wait(s.DrainTimeout)
+
+	select {
+	case <-c.client.Done():
+		return nil
+	default:
+	}
+
+	return errors.Wrap(c.client.Disconnect(&paho.Disconnect{ReasonCode: 0}), "failed to disconnect from mqtt broker")
+}
+
+// connection is a network connection to the broker, on which received messages are acknowledged.
+type connection struct {
+	client   *paho.Client
+	nackedCh chan struct{}
+	nackOnce sync.Once
+
+	mu      sync.Mutex
+	stopped bool
+	acked   bool
+	wg      sync.WaitGroup
+
+	// The client sends acknowledgements in the order messages were received, so only the acked messages
+	// at the head of pending are sent. unsent holds the packet IDs of them until the acknowledgements complete.
+	pending []*paho.Publish
+	acks    map[*paho.Publish]bool
+	unsent  map[uint16]struct{}
+	sentCh  chan struct{}
+}
+
+func (c *connection) receive(p *paho.Publish, f func(subee.Message)) {
+	c.mu.Lock()
+	if p.QoS > 0 && !c.isPending(p.PacketID) {
+		c.pending = append(c.pending, p)
+	}
+	if c.stopped {
+		// Left unacknowledged to be redelivered on the next connection.
+		c.mu.Unlock()
+		return
+	}
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	f(newMessage(p, c))
+}
+
+// isPending reports whether a message with the packet ID is waiting for its acknowledgement to be sent,
+// in which case the client does not track the message again.
+func (c *connection) isPending(id uint16) bool {
+	for _, p := range c.pending {
+		if p.PacketID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *connection) stopReceiving() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+}
+
+func (c *connection) ack(p *paho.Publish) {
+	defer c.wg.Done()
+
+	if p.QoS > 0 {
+		// The sendable acknowledgements are recorded before the client may send them.
+		c.mu.Lock()
+		c.acks[p] = true
+		for len(c.pending) > 0 && c.acks[c.pending[0]] {
+			delete(c.acks, c.pending[0])
+			c.unsent[c.pending[0].PacketID] = struct{}{}
+			c.pending = c.pending[1:]
+		}
+		c.mu.Unlock()
+	}
+
+	// The acknowledgement is not sent after the connection is lost, and then the message is redelivered on the next connection.
+	if err := c.client.Ack(p); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.acked = true
+	c.mu.Unlock()
+}
+
+// nack stops receiving messages and requests reconnection to have the QoS 1 or 2 message redelivered.
+func (c *connection) nack(p *paho.Publish) {
+	defer c.wg.Done()
+
+	if p.QoS == 0 {
+		return
+	}
+
+	c.stopReceiving()
+	c.nackOnce.Do(func() { close(c.nackedCh) })
+}
+
+func (c *connection) hasAcked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.acked
+}
+
+// onSent is called when the acknowledgement of the message with the packet ID completes.
+func (c *connection) onSent(id uint16) {
+	c.mu.Lock()
+	delete(c.unsent, id)
+	c.mu.Unlock()
+
+	select {
+	case c.sentCh <- struct{}{}:
+	default:
+	}
+}
+
+func (c *connection) hasSentAcks() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.unsent) == 0
+}
+
+// wait waits for the received messages to be settled and their acknowledgements to be sent until timeout elapses.
+func (c *connection) wait(timeout time.Duration) {
+	doneCh := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(doneCh)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-doneCh:
+	case <-timer.C:
+		return
+	}
+
+	for !c.hasSentAcks() {
+		select {
+		case <-c.sentCh:
+		case <-c.client.Done():
+			return
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// ackSession notifies the connection of the acknowledgements sent by the client.
+// The acknowledgement of a QoS 2 message completes when PUBCOMP is sent in response to PUBREL.
+type ackSession struct {
+	session.SessionManager
+	conn *connection
+}
+
+func (s *ackSession) Ack(pb *packets.Publish) error {
+	err := s.SessionManager.Ack(pb)
+	if pb.QoS == 1 {
+		s.conn.onSent(pb.PacketID)
+	}
+	return err
+}
+
+func (s *ackSession) PacketReceived(p *packets.ControlPacket, publishCh chan<- *packets.Publish) error {
+	err := s.SessionManager.PacketReceived(p, publishCh)
+	if p.Type == packets.PUBREL {
+		s.conn.onSent(p.PacketID())
+	}
+	return err
+}
@@ -0,0 +1,366 @@
+package mqtt_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/pkg/errors"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/mqtt"
+)
+
+const subscriberID = "subscriber"
+
+func TestSubscriber(t *testing.T) {
+	broker := newBroker(t)
+
+	sub, err := mqtt.CreateSubscriber(broker.dial, newConnect(), []string{"devices/+/telemetry"}, mqtt.WithQoS(2), mqtt.WithSharedGroup("ingest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	receivedCh := make(chan struct{})
+	engine := subee.NewBatch(sub, subee.BatchConsumerFunc(func(ctx context.Context, msgs []subee.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, m := range msgs {
+			received = append(received, string(m.Data()))
+		}
+		if len(received) == 2 {
+			close(receivedCh)
+		}
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)), subee.WithChunkSize(2), subee.WithFlushInterval(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+
+	broker.waitSubscribed(t, "$share/ingest/devices/+/telemetry")
+	broker.publish(t, &paho.Publish{Topic: "devices/a/telemetry", QoS: 2, Payload: []byte("foo")})
+	broker.publish(t, &paho.Publish{Topic: "devices/b/telemetry", QoS: 2, Payload: []byte("bar")})
+
+	select {
+	case <-receivedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages were not consumed")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"foo", "bar"}, received, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("received messages differ: (-want +got)\n%s", diff)
+	}
+	broker.checkSettled(t)
+}
+
+func TestSubscriber_Nack(t *testing.T) {
+	broker := newBroker(t)
+
+	sub, err := mqtt.CreateSubscriber(broker.dial, newConnect(), []string{"devices/#"}, mqtt.WithReconnectBackoff(10*time.Millisecond, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	ackedCh := make(chan struct{})
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("failed")
+		}
+		close(ackedCh)
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+
+	broker.waitSubscribed(t, "devices/#")
+	broker.publish(t, &paho.Publish{Topic: "devices/a/status", QoS: 1, Payload: []byte("online")})
+
+	select {
+	case <-ackedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("nacked message was not redelivered")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := attempts, 2; got != want {
+		t.Errorf("message was consumed %d times, want %d", got, want)
+	}
+	broker.checkSettled(t)
+}
+
+func TestSubscriber_WhenReconnectionRefused(t *testing.T) {
+	broker := newBroker(t)
+
+	var dials int32
+	dial := func(ctx context.Context) (net.Conn, error) {
+		if atomic.AddInt32(&dials, 1) == 2 {
+			return nil, errors.New("connection refused")
+		}
+		return broker.dial(ctx)
+	}
+
+	sub, err := mqtt.CreateSubscriber(dial, newConnect(), []string{"devices/#"}, mqtt.WithReconnectBackoff(10*time.Millisecond, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		attempts int
+	)
+	ackedCh := make(chan struct{})
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("failed")
+		}
+		close(ackedCh)
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+
+	broker.waitSubscribed(t, "devices/#")
+	broker.publish(t, &paho.Publish{Topic: "devices/a/status", QoS: 1, Payload: []byte("online")})
+
+	select {
+	case <-ackedCh:
+	case err := <-errCh:
+		t.Fatalf("Start returned before the message was redelivered: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("nacked message was not redelivered")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	if got, want := atomic.LoadInt32(&dials), int32(3); got != want {
+		t.Errorf("broker was dialed %d times, want %d", got, want)
+	}
+	broker.checkSettled(t)
+}
+
+func TestMessage(t *testing.T) {
+	broker := newBroker(t)
+
+	sub, err := mqtt.CreateSubscriber(broker.dial, newConnect(), []string{"devices/#"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan subee.Message, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- sub.Subscribe(ctx, func(msg subee.Message) { received <- msg }) }()
+
+	broker.waitSubscribed(t, "devices/#")
+	broker.publish(t, &paho.Publish{
+		Topic:   "devices/a/status",
+		QoS:     1,
+		Payload: []byte("online"),
+		Properties: &paho.PublishProperties{
+			User: paho.UserProperties{{Key: "device", Value: "a"}, {Key: mqtt.MetadataTopic, Value: "overwritten"}},
+		},
+	})
+
+	var msg subee.Message
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+
+	if got, want := string(msg.Data()), "online"; got != want {
+		t.Errorf("Data() returned %q, want %q", got, want)
+	}
+	want := map[string]string{
+		"device":              "a",
+		mqtt.MetadataTopic:    "devices/a/status",
+		mqtt.MetadataQoS:      "1",
+		mqtt.MetadataRetained: "false",
+	}
+	if diff := cmp.Diff(want, msg.Metadata()); diff != "" {
+		t.Errorf("Metadata() differs: (-want +got)\n%s", diff)
+	}
+	msg.Ack()
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+	if err := sub.(io.Closer).Close(); err != nil {
+		t.Errorf("Close returned an error: %v", err)
+	}
+	broker.checkSettled(t)
+}
+
+func TestMatchTopic(t *testing.T) {
+	cases := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"devices/a/telemetry", "devices/a/telemetry", true},
+		{"devices/+/telemetry", "devices/a/telemetry", true},
+		{"devices/+/telemetry", "devices/a/status", false},
+		{"devices/+", "devices/a/telemetry", false},
+		{"devices/#", "devices", true},
+		{"devices/#", "devices/a/telemetry", true},
+		{"#", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+		{"$share/ingest/devices/+/telemetry", "devices/a/telemetry", true},
+	}
+
+	for _, c := range cases {
+		if got := mqtt.MatchTopic(c.filter, c.topic); got != c.want {
+			t.Errorf("MatchTopic(%q, %q) returned %t, want %t", c.filter, c.topic, got, c.want)
+		}
+	}
+}
+
+func newConnect() *paho.Connect {
+	expiry := uint32(60)
+	return &paho.Connect{
+		ClientID:   subscriberID,
+		KeepAlive:  30,
+		CleanStart: true,
+		// The embedded broker omits user properties of messages unless problem information is requested.
+		Properties: &paho.ConnectProperties{SessionExpiryInterval: &expiry, RequestProblemInfo: true},
+	}
+}
+
+type broker struct {
+	*mochi.Server
+	addr string
+}
+
+// newBroker starts an embedded broker listening on a loopback address.
+func newBroker(t *testing.T) *broker {
+	t.Helper()
+
+	srv := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := srv.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.EstablishConnection("tcp", conn)
+		}
+	}()
+
+	return &broker{Server: srv, addr: ln.Addr().String()}
+}
+
+func (b *broker) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", b.addr)
+}
+
+func (b *broker) publish(t *testing.T, p *paho.Publish) {
+	t.Helper()
+
+	conn, err := b.dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := paho.NewClient(paho.ClientConfig{Conn: conn})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.Connect(ctx, &paho.Connect{ClientID: "publisher", KeepAlive: 30, CleanStart: true}); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(&paho.Disconnect{ReasonCode: 0})
+
+	if _, err := client.Publish(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (b *broker) waitSubscribed(t *testing.T, filter string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cl, ok := b.Clients.Get(subscriberID); ok {
+			if _, ok := cl.State.Subscriptions.Get(filter); ok {
+				return
+			}
+		}
+	}
+	t.Fatalf("%s was not subscribed", filter)
+}
+
+// checkSettled checks that the subscriber has been disconnected without unacknowledged messages.
+func (b *broker) checkSettled(t *testing.T) {
+	t.Helper()
+
+	cl, ok := b.Clients.Get(subscriberID)
+	if !ok {
+		t.Fatal("session of the subscriber was not found")
+	}
+	for deadline := time.Now().Add(5 * time.Second); !cl.Closed() && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+	}
+	if !cl.Closed() {
+		t.Error("subscriber was not disconnected")
+	}
+	if n := cl.State.Inflight.Len(); n != 0 {
+		t.Errorf("%d messages were left unacknowledged", n)
+	}
+}
@@ -0,0 +1,36 @@
+package mqtt
+
+import "strings"
+
+// MatchTopic reports whether the topic name matches the topic filter with the wildcards '+' and '#'.
+// The prefix of shared subscriptions is ignored.
+// It is useful with subee.Router.HandlePredicate to dispatch messages by MetadataTopic.
+func MatchTopic(filter, topic string) bool {
+	if strings.HasPrefix(filter, "$share/") {
+		parts := strings.SplitN(filter, "/", 3)
+		if len(parts) < 3 {
+			return false
+		}
+		filter = parts[2]
+	}
+
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+
+	// Topics beginning with '$' are not matched by wildcards at the first level.
+	if strings.HasPrefix(topic, "$") && (fs[0] == "+" || fs[0] == "#") {
+		return false
+	}
+
+	for i, f := range fs {
+		switch {
+		case f == "#":
+			return true
+		case i >= len(ts):
+			return false
+		case f != "+" && f != ts[i]:
+			return false
+		}
+	}
+
+	return len(fs) == len(ts)
+}
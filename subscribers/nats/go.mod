@@ -0,0 +1,20 @@
+module github.com/wantedly/subee/subscribers/nats
+
+go 1.23.0
+
+require (
+	github.com/google/go-cmp v0.5.9
+	github.com/nats-io/nats.go v1.42.0
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+)
+
+require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/nats-io/nkeys v0.4.11 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
+)
+
+replace github.com/wantedly/subee => ../..
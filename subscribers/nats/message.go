@@ -0,0 +1,86 @@
+package nats
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Message is wrapps jetstream.Msg
+type Message struct {
+	jetstream.Msg
+	metadata map[string]string
+	jsMeta   *jetstream.MsgMetadata
+	nakDelay time.Duration
+}
+
+func newMessage(m jetstream.Msg, nakDelay time.Duration) *Message {
+	headers := m.Headers()
+	metadata := make(map[string]string, len(headers))
+	for k, vs := range headers {
+		if len(vs) > 0 {
+			metadata[k] = vs[0]
+		}
+	}
+
+	// jsMeta is nil if the reply subject is not of JetStream.
+	jsMeta, _ := m.Metadata()
+
+	return &Message{
+		Msg:      m,
+		metadata: metadata,
+		jsMeta:   jsMeta,
+		nakDelay: nakDelay,
+	}
+}
+
+// Metadata returns message headers.
+// When a header has multiple values, the first one is used.
+// JetStream metadata of the message can be retrieved by Msg.Metadata.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// MessageID returns the Nats-Msg-Id header used for deduplication,
+// or the stream sequence in the form of "<stream>/<sequence>" if the header is not set.
+func (m *Message) MessageID() string {
+	if id := m.Headers().Get(nats.MsgIdHdr); id != "" {
+		return id
+	}
+	if m.jsMeta == nil {
+		return ""
+	}
+	return m.jsMeta.Stream + "/" + strconv.FormatUint(m.jsMeta.Sequence.Stream, 10)
+}
+
+// PublishTime returns the time when the message was stored in the stream.
+func (m *Message) PublishTime() time.Time {
+	if m.jsMeta == nil {
+		return time.Time{}
+	}
+	return m.jsMeta.Timestamp
+}
+
+// DeliveryAttempt returns the number of times the message has been delivered.
+func (m *Message) DeliveryAttempt() int {
+	if m.jsMeta == nil {
+		return 0
+	}
+	return int(m.jsMeta.NumDelivered)
+}
+
+// OrderingKey returns an empty string because JetStream orders messages by stream sequence.
+func (m *Message) OrderingKey() string { return "" }
+
+// Ack acknowledges the message.
+func (m *Message) Ack() { m.Msg.Ack() }
+
+// Nack negatively acknowledges the message so that it is redelivered.
+// The redelivery is delayed when WithNakDelay is given.
+func (m *Message) Nack() {
+	if m.nakDelay > 0 {
+		m.Msg.NakWithDelay(m.nakDelay)
+		return
+	}
+	m.Msg.Nak()
+}
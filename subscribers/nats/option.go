@@ -0,0 +1,81 @@
+package nats
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DefaultQueueGroupInactiveThreshold is the default duration after which the consumer of a queue group is removed
+// when no subscribers are active.
+const DefaultQueueGroupInactiveThreshold = 5 * time.Minute
+
+// Config represents subscriber configuration.
+type Config struct {
+	ConnectOpts    []nats.Option
+	ConsumerConfig jetstream.ConsumerConfig
+	ConsumeOpts    []jetstream.PullConsumeOpt
+	Durable        string
+	QueueGroup     string
+	NakDelay       time.Duration
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithConnectOptions returns an Option that set nats.Option(s) to connect to the server.
+func WithConnectOptions(opts ...nats.Option) Option {
+	return func(c *Config) {
+		c.ConnectOpts = append(c.ConnectOpts, opts...)
+	}
+}
+
+// WithConsumerConfig returns an Option that set jetstream.ConsumerConfig to create the consumer.
+// The name and the ack policy are overwritten by the subscriber.
+func WithConsumerConfig(cfg jetstream.ConsumerConfig) Option {
+	return func(c *Config) {
+		c.ConsumerConfig = cfg
+	}
+}
+
+// WithConsumeOptions returns an Option that set jetstream.PullConsumeOpt(s) to pull messages.
+func WithConsumeOptions(opts ...jetstream.PullConsumeOpt) Option {
+	return func(c *Config) {
+		c.ConsumeOpts = append(c.ConsumeOpts, opts...)
+	}
+}
+
+// WithDurable returns an Option that subscribes with the durable consumer.
+// The consumer and its progress are kept on the server after subscribers stop,
+// and subscribers with the same durable name share messages.
+func WithDurable(name string) Option {
+	return func(c *Config) {
+		c.Durable = name
+		c.QueueGroup = ""
+	}
+}
+
+// WithQueueGroup returns an Option that subscribes as a member of the queue group.
+// Members of the group share a consumer and each message is delivered to one of them.
+// The consumer is removed from the server when no members are active for the inactive threshold of ConsumerConfig,
+// or DefaultQueueGroupInactiveThreshold if it is not set.
+func WithQueueGroup(name string) Option {
+	return func(c *Config) {
+		c.QueueGroup = name
+		c.Durable = ""
+	}
+}
+
+// WithNakDelay returns an Option that set the delay before nacked messages are redelivered.
+func WithNakDelay(d time.Duration) Option {
+	return func(c *Config) {
+		c.NakDelay = d
+	}
+}
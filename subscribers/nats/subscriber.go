@@ -0,0 +1,141 @@
+package nats
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+type subscriberImpl struct {
+	*Config
+	consumer jetstream.Consumer
+	conn     *nats.Conn
+}
+
+// CreateSubscriber returns Subscriber implementation that pulls messages from the JetStream stream.
+// Either WithDurable or WithQueueGroup is required, and the consumer is created or updated with ConsumerConfig.
+// The connection is closed on Close, so that messages received before Subscribe returns can still be acked.
+func CreateSubscriber(ctx context.Context, url, stream string, opts ...Option) (subee.Subscriber, error) {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	if len(stream) == 0 {
+		return nil, errors.New("missing jetstream stream name")
+	}
+
+	consumerCfg, err := cfg.createConsumerConfig()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	conn, err := nats.Connect(url, cfg.ConnectOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to nats server")
+	}
+
+	consumer, err := createConsumer(ctx, conn, stream, consumerCfg)
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "failed to create jetstream consumer")
+	}
+
+	return &subscriberImpl{
+		Config:   cfg,
+		consumer: consumer,
+		conn:     conn,
+	}, nil
+}
+
+// NewSubscriber returns Subscriber implementation that pulls messages from the existing consumer.
+// The consumer must be configured with jetstream.AckExplicitPolicy, and its connection is not closed by the subscriber.
+func NewSubscriber(consumer jetstream.Consumer, opts ...Option) subee.Subscriber {
+	cfg := new(Config)
+	cfg.apply(opts)
+
+	return &subscriberImpl{
+		Config:   cfg,
+		consumer: consumer,
+	}
+}
+
+// Close closes the connection created by CreateSubscriber.
+func (s *subscriberImpl) Close() error {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	return nil
+}
+
+func (c *Config) createConsumerConfig() (jetstream.ConsumerConfig, error) {
+	cfg := c.ConsumerConfig
+	cfg.AckPolicy = jetstream.AckExplicitPolicy
+
+	switch {
+	case len(c.Durable) > 0:
+		cfg.Name = c.Durable
+		cfg.Durable = c.Durable
+	case len(c.QueueGroup) > 0:
+		cfg.Name = c.QueueGroup
+		cfg.Durable = ""
+		if cfg.InactiveThreshold == 0 {
+			cfg.InactiveThreshold = DefaultQueueGroupInactiveThreshold
+		}
+	default:
+		return cfg, errors.New("missing durable name or queue group")
+	}
+
+	return cfg, nil
+}
+
+func createConsumer(ctx context.Context, conn *nats.Conn, stream string, cfg jetstream.ConsumerConfig) (jetstream.Consumer, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create jetstream context")
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, cfg)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create or update consumer %q on stream %q", cfg.Name, stream)
+	}
+
+	return consumer, nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	var (
+		mu      sync.Mutex
+		lastErr error
+	)
+	opts := append([]jetstream.PullConsumeOpt{
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			lastErr = err
+		}),
+	}, s.ConsumeOpts...)
+
+	cc, err := s.consumer.Consume(func(m jetstream.Msg) {
+		f(newMessage(m, s.NakDelay))
+	}, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to consume jetstream messages")
+	}
+
+	select {
+	case <-ctx.Done():
+		cc.Drain()
+		<-cc.Closed()
+		return nil
+	case <-cc.Closed():
+		mu.Lock()
+		defer mu.Unlock()
+		if lastErr != nil {
+			return errors.Wrap(lastErr, "jetstream consumer was closed")
+		}
+		return errors.New("jetstream consumer was closed unexpectedly")
+	}
+}
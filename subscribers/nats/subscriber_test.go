@@ -0,0 +1,193 @@
+package nats_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/wantedly/subee"
+	subee_nats "github.com/wantedly/subee/subscribers/nats"
+)
+
+func TestSubscriber(t *testing.T) {
+	in := []*fakeMsg{
+		{data: []byte("foo"), meta: &jetstream.MsgMetadata{Stream: "test-stream", Sequence: jetstream.SequencePair{Stream: 1}, NumDelivered: 1}},
+		{data: []byte("bar"), headers: nats.Header{"corge": {"12", "13"}, "id": {"aaabbbccc"}, nats.MsgIdHdr: {"msg-2"}}},
+		{data: []byte("baz")},
+	}
+
+	consumer := newFakeConsumer()
+	subscriber := subee_nats.NewSubscriber(consumer, subee_nats.WithNakDelay(time.Second))
+
+	type Msg struct {
+		Data    []byte
+		Meta    map[string]string
+		ID      string
+		Attempt int
+	}
+
+	var out []Msg
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) {
+			em := msg.(subee.ExtendedMessage)
+			out = append(out, Msg{Data: msg.Data(), Meta: msg.Metadata(), ID: em.MessageID(), Attempt: em.DeliveryAttempt()})
+			if string(msg.Data()) == "bar" {
+				msg.Nack()
+				return
+			}
+			msg.Ack()
+		})
+	}()
+
+	for _, m := range in {
+		consumer.deliver(m)
+	}
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	want := []Msg{
+		{Data: []byte("foo"), Meta: map[string]string{}, ID: "test-stream/1", Attempt: 1},
+		{Data: []byte("bar"), Meta: map[string]string{"corge": "12", "id": "aaabbbccc", nats.MsgIdHdr: "msg-2"}, ID: "msg-2"},
+		{Data: []byte("baz"), Meta: map[string]string{}},
+	}
+	if diff := cmp.Diff(want, out); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+
+	for _, m := range []*fakeMsg{in[0], in[2]} {
+		if !m.acked {
+			t.Errorf("message %q was not acked", m.data)
+		}
+	}
+	if got, want := in[1].nakDelay, time.Second; got != want {
+		t.Errorf("message %q was nacked with delay %v, want %v", in[1].data, got, want)
+	}
+	if !consumer.drained {
+		t.Error("consumer was not drained")
+	}
+}
+
+func TestSubscriber_WhenConsumerClosed(t *testing.T) {
+	consumer := newFakeConsumer()
+	subscriber := subee_nats.NewSubscriber(consumer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(context.Background(), func(subee.Message) {})
+	}()
+
+	consumer.Stop()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("Subscribe returned nil, want an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe did not return after the consumer was closed")
+	}
+}
+
+func TestCreateSubscriber_WhenNoConsumerName(t *testing.T) {
+	_, err := subee_nats.CreateSubscriber(context.Background(), nats.DefaultURL, "test-stream")
+	if err == nil {
+		t.Error("CreateSubscriber returned nil, want an error")
+	}
+}
+
+type fakeMsg struct {
+	jetstream.Msg
+	data     []byte
+	headers  nats.Header
+	meta     *jetstream.MsgMetadata
+	acked    bool
+	nakDelay time.Duration
+}
+
+func (m *fakeMsg) Data() []byte { return m.data }
+
+func (m *fakeMsg) Headers() nats.Header { return m.headers }
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	if m.meta == nil {
+		return nil, jetstream.ErrNotJSMessage
+	}
+	return m.meta, nil
+}
+
+func (m *fakeMsg) Ack() error {
+	m.acked = true
+	return nil
+}
+
+func (m *fakeMsg) Nak() error { return m.NakWithDelay(0) }
+
+func (m *fakeMsg) NakWithDelay(d time.Duration) error {
+	m.nakDelay = d
+	return nil
+}
+
+// fakeConsumer implements jetstream.Consumer and jetstream.ConsumeContext.
+type fakeConsumer struct {
+	jetstream.Consumer
+	msgCh     chan jetstream.Msg
+	startedCh chan struct{}
+	stopCh    chan struct{}
+	closedCh  chan struct{}
+	stopOnce  sync.Once
+	drained   bool
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{
+		msgCh:     make(chan jetstream.Msg),
+		startedCh: make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		closedCh:  make(chan struct{}),
+	}
+}
+
+func (c *fakeConsumer) Consume(handler jetstream.MessageHandler, opts ...jetstream.PullConsumeOpt) (jetstream.ConsumeContext, error) {
+	go func() {
+		defer close(c.closedCh)
+		for {
+			select {
+			case m := <-c.msgCh:
+				handler(m)
+			case <-c.stopCh:
+				return
+			}
+		}
+	}()
+	close(c.startedCh)
+	return c, nil
+}
+
+// deliver blocks until the message is passed to the handler.
+func (c *fakeConsumer) deliver(m jetstream.Msg) {
+	<-c.startedCh
+	c.msgCh <- m
+}
+
+func (c *fakeConsumer) Stop() {
+	<-c.startedCh
+	c.stopOnce.Do(func() { close(c.stopCh) })
+}
+
+func (c *fakeConsumer) Drain() {
+	c.drained = true
+	c.Stop()
+}
+
+func (c *fakeConsumer) Closed() <-chan struct{} { return c.closedCh }
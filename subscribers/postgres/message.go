@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Message is a message fetched from the queue table.
+type Message struct {
+	ID        int64
+	CreatedAt time.Time
+	// Attempts is the number of times the message has been fetched, including this delivery.
+	Attempts int
+
+	data     []byte
+	metadata map[string]string
+
+	sub        *subscriberImpl
+	settleOnce sync.Once
+}
+
+// Data returns the message data.
+func (m *Message) Data() []byte { return m.data }
+
+// Metadata returns the message metadata.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// MessageID returns the ID of the row.
+func (m *Message) MessageID() string { return strconv.FormatInt(m.ID, 10) }
+
+// PublishTime returns the time when the row was inserted.
+func (m *Message) PublishTime() time.Time { return m.CreatedAt }
+
+// DeliveryAttempt returns Attempts.
+func (m *Message) DeliveryAttempt() int { return m.Attempts }
+
+// OrderingKey returns an empty string because the queue table does not order messages by key.
+func (m *Message) OrderingKey() string { return "" }
+
+// Ack deletes the message from the queue table, or marks it as done when WithRetainAcked is set.
+// It has no effect if the message has been redelivered after the visibility timeout.
+func (m *Message) Ack() {
+	if !m.settle() {
+		return
+	}
+
+	query := `DELETE FROM %s WHERE id = $1 AND attempts = $2`
+	if m.sub.RetainAcked {
+		query = `UPDATE %s SET done_at = now() WHERE id = $1 AND attempts = $2`
+	}
+	m.exec(query, "ack")
+}
+
+// Nack makes the message visible again after the backoff set by WithNackBackoff.
+// It has no effect if the message has been redelivered after the visibility timeout.
+func (m *Message) Nack() {
+	if !m.settle() {
+		return
+	}
+
+	m.exec(`UPDATE %s SET visible_at = now() + $3::float8 * interval '1 second' WHERE id = $1 AND attempts = $2`, "nack",
+		m.sub.nackBackoff(m.Attempts).Seconds())
+}
+
+func (m *Message) settle() (ok bool) {
+	m.settleOnce.Do(func() { ok = true })
+	return
+}
+
+// exec executes the query on the message, and reports the error to ErrorHandler.
+// The message is redelivered after the visibility timeout even if the query fails.
+func (m *Message) exec(query, op string, args ...any) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.sub.VisibilityTimeout)
+	defer cancel()
+
+	args = append([]any{m.ID, m.Attempts}, args...)
+	if _, err := m.sub.pool.Exec(ctx, fmt.Sprintf(query, m.sub.table), args...); err != nil {
+		m.sub.ErrorHandler(errors.Wrapf(err, "failed to %s message %d", op, m.ID))
+	}
+}
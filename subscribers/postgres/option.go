@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"log"
+	"time"
+
+	"github.com/wantedly/subee/internal/backoff"
+)
+
+const (
+	// DefaultBatchSize is the default maximum number of messages fetched at once.
+	DefaultBatchSize = 10
+	// DefaultVisibilityTimeout is the default duration that fetched messages are hidden from other subscribers.
+	DefaultVisibilityTimeout = 30 * time.Second
+	// DefaultPollInterval is the default interval to poll the queue table without notifications.
+	DefaultPollInterval = 5 * time.Second
+	// DefaultNackInitialBackoff is the default delay to redeliver a message nacked for the first time.
+	DefaultNackInitialBackoff = time.Second
+	// DefaultNackMaxBackoff is the default maximum delay to redeliver nacked messages.
+	DefaultNackMaxBackoff = 5 * time.Minute
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	Channel           string
+	BatchSize         int
+	VisibilityTimeout time.Duration
+	PollInterval      time.Duration
+	RetainAcked       bool
+
+	NackInitialBackoff time.Duration
+	NackMaxBackoff     time.Duration
+
+	ErrorHandler func(error)
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		BatchSize:          DefaultBatchSize,
+		VisibilityTimeout:  DefaultVisibilityTimeout,
+		PollInterval:       DefaultPollInterval,
+		NackInitialBackoff: DefaultNackInitialBackoff,
+		NackMaxBackoff:     DefaultNackMaxBackoff,
+		ErrorHandler:       func(err error) { log.Print(err) },
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithChannel returns an Option that set the channel to LISTEN for wakeups.
+// The table name is used by default, which is the channel Publish notifies.
+func WithChannel(channel string) Option {
+	return func(c *Config) {
+		c.Channel = channel
+	}
+}
+
+// WithBatchSize returns an Option that set the maximum number of messages fetched at once.
+func WithBatchSize(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.BatchSize = n
+		}
+	}
+}
+
+// WithVisibilityTimeout returns an Option that set the duration that fetched messages are hidden from other subscribers.
+// Messages neither acked nor nacked within the timeout are redelivered.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.VisibilityTimeout = d
+		}
+	}
+}
+
+// WithPollInterval returns an Option that set the interval to poll the queue table.
+// It bounds the delivery latency of messages inserted without notifications.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.PollInterval = d
+		}
+	}
+}
+
+// WithRetainAcked returns an Option that marks acked messages as done instead of deleting them.
+func WithRetainAcked() Option {
+	return func(c *Config) {
+		c.RetainAcked = true
+	}
+}
+
+// WithNackBackoff returns an Option that set the delay to redeliver nacked messages.
+// It starts with initial and doubles on each attempt of the message, up to max.
+// The backoff is not capped if max is not positive.
+func WithNackBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		c.NackInitialBackoff = initial
+		c.NackMaxBackoff = max
+	}
+}
+
+// WithErrorHandler returns an Option that set the function called with errors of acking and nacking messages,
+// which are not returned by Subscribe.
+// The errors are logged with the standard logger by default.
+func WithErrorHandler(f func(error)) Option {
+	return func(c *Config) {
+		if f != nil {
+			c.ErrorHandler = f
+		}
+	}
+}
+
+// nackBackoff returns the delay to redeliver the message nacked on the attempt.
+func (c *Config) nackBackoff(attempts int) time.Duration {
+	return backoff.Exponential(c.NackInitialBackoff, c.NackMaxBackoff, attempts-1)
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+type subscriberImpl struct {
+	*Config
+	pool  *pgxpool.Pool
+	table string
+}
+
+// CreateSubscriber returns Subscriber implementation that fetches messages from the queue table created by CreateTable.
+// Messages are locked with SELECT ... FOR UPDATE SKIP LOCKED, so multiple subscribers can share the table.
+// Subscribers are woken up by notifications on the channel, and poll the table at PollInterval.
+func CreateSubscriber(pool *pgxpool.Pool, table string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if len(table) == 0 {
+		return nil, errors.New("missing queue table name")
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = table
+	}
+
+	return &subscriberImpl{
+		Config: cfg,
+		pool:   pool,
+		table:  quoteTable(table),
+	}, nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wakeCh := make(chan struct{}, 1)
+	listenErrCh := make(chan error, 1)
+	go func() {
+		listenErrCh <- s.listen(ctx, wakeCh)
+	}()
+
+	timer := time.NewTimer(s.PollInterval)
+	defer timer.Stop()
+
+	for {
+		msgs, err := s.fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+
+		for _, msg := range msgs {
+			f(msg)
+		}
+
+		// More messages may be ready.
+		if len(msgs) == s.BatchSize {
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.PollInterval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-listenErrCh:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.WithStack(err)
+		case <-wakeCh:
+		case <-timer.C:
+		}
+	}
+}
+
+// listen waits for notifications on the channel with a dedicated connection, and signals wakeCh.
+func (s *subscriberImpl) listen(ctx context.Context, wakeCh chan<- struct{}) error {
+	pooled, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire connection to listen")
+	}
+	// The connection is no longer usable after WaitForNotification is interrupted, so it is not returned to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{s.Channel}.Sanitize()); err != nil {
+		return errors.Wrapf(err, "failed to listen %s", s.Channel)
+	}
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return errors.Wrap(err, "failed to wait for notification")
+		}
+		select {
+		case wakeCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// fetch locks visible messages and hides them for the visibility timeout.
+func (s *subscriberImpl) fetch(ctx context.Context) ([]*Message, error) {
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		WITH next AS (
+			SELECT id FROM %[1]s
+			WHERE done_at IS NULL AND visible_at <= now()
+			ORDER BY visible_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), fetched AS (
+			UPDATE %[1]s AS q
+			SET attempts = q.attempts + 1, visible_at = now() + $2::float8 * interval '1 second'
+			FROM next WHERE q.id = next.id
+			RETURNING q.id, q.data, q.metadata, q.attempts, q.created_at
+		)
+		SELECT * FROM fetched ORDER BY id
+	`, s.table), s.BatchSize, s.VisibilityTimeout.Seconds())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch messages")
+	}
+
+	msgs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Message, error) {
+		m := &Message{sub: s}
+		err := row.Scan(&m.ID, &m.data, &m.metadata, &m.Attempts, &m.CreatedAt)
+		return m, err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch messages")
+	}
+
+	return msgs, nil
+}
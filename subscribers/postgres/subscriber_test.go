@@ -0,0 +1,199 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/postgres"
+)
+
+// setup connects to the database at SUBEE_POSTGRES_URL and creates a fresh queue table.
+func setup(t *testing.T) (*pgxpool.Pool, string) {
+	t.Helper()
+
+	url := os.Getenv("SUBEE_POSTGRES_URL")
+	if url == "" {
+		t.Skip("SUBEE_POSTGRES_URL is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	table := fmt.Sprintf("subee_test_%d", time.Now().UnixNano())
+	if err := postgres.CreateTable(ctx, pool, table); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Exec(context.Background(), "DROP TABLE "+table) })
+
+	return pool, table
+}
+
+func TestSubscriber(t *testing.T) {
+	pool, table := setup(t)
+	ctx := context.Background()
+
+	sub, err := postgres.CreateSubscriber(pool, table,
+		postgres.WithNackBackoff(50*time.Millisecond, time.Second),
+		postgres.WithPollInterval(time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string][]int)
+		done     = make(chan struct{})
+	)
+	engine := subee.New(sub, subee.ConsumerFunc(func(ctx context.Context, msg subee.Message) error {
+		m := msg.(*postgres.Message)
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(m.Data())] = append(attempts[string(m.Data())], m.Attempts)
+
+		if string(m.Data()) == "bar" && m.Attempts < 3 {
+			return errors.New("error")
+		}
+		if len(attempts["foo"]) == 1 && len(attempts["bar"]) == 3 {
+			close(done)
+		}
+		return nil
+	}), subee.WithLogger(log.New(ioutil.Discard, "", 0)))
+
+	ctx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() { errCh <- engine.Start(ctx) }()
+
+	// Wait for the subscriber to listen, then publish with notifications.
+	time.Sleep(100 * time.Millisecond)
+	for _, data := range []string{"foo", "bar"} {
+		if _, err := postgres.Publish(ctx, pool, table, []byte(data), map[string]string{"key": data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages were not consumed")
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Start returned an error: %v", err)
+	}
+
+	want := map[string][]int{"foo": {1}, "bar": {1, 2, 3}}
+	if diff := cmp.Diff(want, attempts); diff != "" {
+		t.Errorf("attempts differ: (-want +got)\n%s", diff)
+	}
+
+	var remaining int
+	if err := pool.QueryRow(context.Background(), "SELECT count(*) FROM "+table).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Errorf("%d messages remain in the table, want 0", remaining)
+	}
+}
+
+func TestSubscriber_WhenVisibilityTimeoutExpired(t *testing.T) {
+	pool, table := setup(t)
+	ctx := context.Background()
+
+	if _, err := postgres.Publish(ctx, pool, table, []byte("foo"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := postgres.CreateSubscriber(pool, table,
+		postgres.WithVisibilityTimeout(100*time.Millisecond),
+		postgres.WithPollInterval(50*time.Millisecond),
+		postgres.WithRetainAcked(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *postgres.Message, 2)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go sub.Subscribe(ctx, func(msg subee.Message) { received <- msg.(*postgres.Message) })
+
+	first := <-received
+	second := <-received
+	if first.ID != second.ID || second.Attempts != 2 {
+		t.Fatalf("message was not redelivered: got attempts %d", second.Attempts)
+	}
+
+	// The ack of the expired delivery must not settle the redelivered one.
+	first.Ack()
+	var doneAt *time.Time
+	if err := pool.QueryRow(context.Background(), "SELECT done_at FROM "+table).Scan(&doneAt); err != nil {
+		t.Fatal(err)
+	}
+	if doneAt != nil {
+		t.Error("message acked by the expired delivery")
+	}
+
+	second.Ack()
+	if err := pool.QueryRow(context.Background(), "SELECT done_at FROM "+table).Scan(&doneAt); err != nil {
+		t.Fatal(err)
+	}
+	if doneAt == nil {
+		t.Error("message was not marked as done")
+	}
+}
+
+func TestMessage_WhenAckFailed(t *testing.T) {
+	pool, table := setup(t)
+	ctx := context.Background()
+
+	if _, err := postgres.Publish(ctx, pool, table, []byte("foo"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	sub, err := postgres.CreateSubscriber(pool, table,
+		postgres.WithPollInterval(50*time.Millisecond),
+		postgres.WithErrorHandler(func(err error) { errCh <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan subee.Message, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go sub.Subscribe(ctx, func(msg subee.Message) { received <- msg })
+
+	msg := <-received
+	cancel()
+	if _, err := pool.Exec(context.Background(), "DROP TABLE "+table); err != nil {
+		t.Fatal(err)
+	}
+	msg.Ack()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("ErrorHandler was called with nil")
+		}
+	default:
+		t.Error("ErrorHandler was not called")
+	}
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// Querier is the interface to execute queries.
+// *pgxpool.Pool, *pgx.Conn and pgx.Tx implement it.
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// CreateTable creates the queue table and its index if they do not exist.
+// The table name can be qualified with the schema.
+func CreateTable(ctx context.Context, db Querier, table string) error {
+	parts := strings.Split(table, ".")
+	index := pgx.Identifier{parts[len(parts)-1] + "_visible_at_idx"}.Sanitize()
+
+	_, err := db.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %[1]s (
+			id         BIGSERIAL PRIMARY KEY,
+			data       BYTEA NOT NULL,
+			metadata   JSONB NOT NULL DEFAULT '{}',
+			attempts   INTEGER NOT NULL DEFAULT 0,
+			visible_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+			done_at    TIMESTAMPTZ
+		);
+		CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (visible_at, id) WHERE done_at IS NULL;
+	`, quoteTable(table), index))
+
+	return errors.Wrap(err, "failed to create queue table")
+}
+
+// Publish inserts a message into the queue table and notifies subscribers listening the table name.
+// When db is a transaction, the message becomes visible and the notification is delivered on commit,
+// so it can be used as a transactional outbox.
+func Publish(ctx context.Context, db Querier, table string, data []byte, metadata map[string]string) (id int64, err error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	err = db.QueryRow(ctx, fmt.Sprintf(`
+		WITH inserted AS (
+			INSERT INTO %s (data, metadata) VALUES ($1, $2) RETURNING id
+		)
+		SELECT id, pg_notify($3, '') FROM inserted
+	`, quoteTable(table)), data, metadata, table).Scan(&id, nil)
+
+	return id, errors.Wrap(err, "failed to publish message")
+}
+
+func quoteTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
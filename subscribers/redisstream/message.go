@@ -0,0 +1,67 @@
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Message is wrapps redis.XMessage
+type Message struct {
+	redis.XMessage
+	sub      *subscriberImpl
+	data     []byte
+	metadata map[string]string
+}
+
+func newMessage(m redis.XMessage, s *subscriberImpl) *Message {
+	msg := &Message{
+		XMessage: m,
+		sub:      s,
+		metadata: make(map[string]string, len(m.Values)),
+	}
+	for k, v := range m.Values {
+		if k == s.DataField {
+			msg.data = []byte(fmt.Sprint(v))
+			continue
+		}
+		msg.metadata[k] = fmt.Sprint(v)
+	}
+	return msg
+}
+
+// Data returns the value of the data field.
+func (m *Message) Data() []byte { return m.data }
+
+// Metadata returns the stream fields except the data field.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// MessageID returns the entry ID.
+func (m *Message) MessageID() string { return m.ID }
+
+// PublishTime returns the time in milliseconds part of the entry ID.
+func (m *Message) PublishTime() time.Time {
+	ms, err := strconv.ParseInt(strings.SplitN(m.ID, "-", 2)[0], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// DeliveryAttempt returns 0 because XREADGROUP and XAUTOCLAIM do not report delivery counts.
+func (m *Message) DeliveryAttempt() int { return 0 }
+
+// OrderingKey returns an empty string because stream entries are ordered by ID.
+func (m *Message) OrderingKey() string { return "" }
+
+// Ack acknowledges the entry with XACK.
+func (m *Message) Ack() {
+	m.sub.client.XAck(context.Background(), m.sub.stream, m.sub.group, m.ID)
+}
+
+// Nack leaves the entry pending so that it is reclaimed after it has been idle for the claim threshold.
+func (m *Message) Nack() {}
@@ -0,0 +1,93 @@
+package redisstream
+
+import "time"
+
+const (
+	// DefaultDataField is the default stream field used as the message payload.
+	DefaultDataField = "data"
+	// DefaultBatchSize is the default maximum number of entries read at once.
+	DefaultBatchSize = 10
+	// DefaultBlock is the default duration to block for new entries.
+	DefaultBlock = time.Second
+	// DefaultClaimMinIdle is the default idle time after which pending entries are reclaimed.
+	DefaultClaimMinIdle = 5 * time.Minute
+	// DefaultClaimInterval is the default interval to reclaim pending entries.
+	DefaultClaimInterval = time.Minute
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	DataField     string
+	BatchSize     int64
+	Block         time.Duration
+	StartID       string
+	ClaimMinIdle  time.Duration
+	ClaimInterval time.Duration
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		DataField:     DefaultDataField,
+		BatchSize:     DefaultBatchSize,
+		Block:         DefaultBlock,
+		StartID:       "$",
+		ClaimMinIdle:  DefaultClaimMinIdle,
+		ClaimInterval: DefaultClaimInterval,
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithDataField returns an Option that set the stream field used as Message.Data.
+// The other fields are exposed as Message.Metadata.
+func WithDataField(field string) Option {
+	return func(c *Config) {
+		c.DataField = field
+	}
+}
+
+// WithBatchSize returns an Option that set the maximum number of entries read at once.
+func WithBatchSize(n int64) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.BatchSize = n
+		}
+	}
+}
+
+// WithBlock returns an Option that set the duration to block for new entries.
+// It also bounds how long Subscribe takes to return after the context is done.
+func WithBlock(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.Block = d
+		}
+	}
+}
+
+// WithStartID returns an Option that set the ID from which the consumer group reads when it is created.
+// "$" is used by default to read only new entries, and "0" reads the whole stream.
+func WithStartID(id string) Option {
+	return func(c *Config) {
+		c.StartID = id
+	}
+}
+
+// WithReclaim returns an Option that set how pending entries are reclaimed.
+// Entries delivered but not acked for minIdle, including nacked ones and ones left by dead consumers,
+// are claimed and redelivered every interval. Reclaiming is disabled when minIdle is not positive.
+func WithReclaim(minIdle, interval time.Duration) Option {
+	return func(c *Config) {
+		c.ClaimMinIdle = minIdle
+		if interval > 0 {
+			c.ClaimInterval = interval
+		}
+	}
+}
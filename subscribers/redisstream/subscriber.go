@@ -0,0 +1,151 @@
+package redisstream
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/wantedly/subee"
+)
+
+type subscriberImpl struct {
+	*Config
+	client   redis.UniversalClient
+	stream   string
+	group    string
+	consumer string
+}
+
+// CreateSubscriber returns Subscriber implementation that reads the stream as the consumer of the consumer group.
+// The consumer group, and the stream if needed, are created when they do not exist.
+// Reclaiming pending entries requires Redis 6.2 or later.
+func CreateSubscriber(ctx context.Context, client redis.UniversalClient, stream, group, consumer string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if len(stream) == 0 {
+		return nil, errors.New("missing redis stream key")
+	}
+	if len(group) == 0 {
+		return nil, errors.New("missing redis stream consumer group")
+	}
+	if len(consumer) == 0 {
+		return nil, errors.New("missing redis stream consumer name")
+	}
+
+	err := client.XGroupCreateMkStream(ctx, stream, group, cfg.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, errors.Wrap(err, "failed to create redis stream consumer group")
+	}
+
+	return &subscriberImpl{
+		Config:   cfg,
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+	}, nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		claimErr error
+	)
+	if s.ClaimMinIdle > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+			claimErr = s.reclaim(ctx, f)
+		}()
+	}
+
+	err := s.read(ctx, f)
+	cancel()
+	wg.Wait()
+
+	if err == nil {
+		err = claimErr
+	}
+
+	return errors.WithStack(err)
+}
+
+// read delivers new entries with XREADGROUP until ctx is done.
+func (s *subscriberImpl) read(ctx context.Context, f func(subee.Message)) error {
+	for ctx.Err() == nil {
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, ">"},
+			Count:    s.BatchSize,
+			Block:    s.Block,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to read redis stream")
+		}
+
+		for _, stream := range streams {
+			for _, m := range stream.Messages {
+				f(newMessage(m, s))
+			}
+		}
+	}
+
+	return nil
+}
+
+// reclaim redelivers entries idle past ClaimMinIdle with XAUTOCLAIM every ClaimInterval until ctx is done.
+func (s *subscriberImpl) reclaim(ctx context.Context, f func(subee.Message)) error {
+	ticker := time.NewTicker(s.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		for start := "0-0"; ; {
+			msgs, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   s.stream,
+				Group:    s.group,
+				Consumer: s.consumer,
+				MinIdle:  s.ClaimMinIdle,
+				Start:    start,
+				Count:    s.BatchSize,
+			}).Result()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.Wrap(err, "failed to reclaim pending entries of redis stream")
+			}
+
+			for _, m := range msgs {
+				if m.Values != nil {
+					f(newMessage(m, s))
+				}
+			}
+
+			if next == "0-0" || next == "" {
+				break
+			}
+			start = next
+		}
+	}
+}
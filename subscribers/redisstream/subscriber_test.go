@@ -0,0 +1,155 @@
+package redisstream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/go-cmp/cmp"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/wantedly/subee"
+	"github.com/wantedly/subee/subscribers/redisstream"
+)
+
+func setup(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return m, client
+}
+
+func TestSubscriber(t *testing.T) {
+	orDie := func(t *testing.T, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	ctx := context.Background()
+	_, client := setup(t)
+
+	type Msg struct {
+		Data []byte
+		Meta map[string]string
+	}
+
+	in := []map[string]interface{}{
+		{"body": "foo"},
+		{"body": "bar", "corge": "12", "id": "aaabbbccc"},
+		{"body": "baz"},
+	}
+	for _, values := range in {
+		orDie(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "test-stream", Values: values}).Err())
+	}
+
+	subscriber, err := redisstream.CreateSubscriber(
+		ctx, client, "test-stream", "test-group", "test-consumer",
+		redisstream.WithStartID("0"),
+		redisstream.WithDataField("body"),
+		redisstream.WithBlock(10*time.Millisecond),
+	)
+	orDie(t, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var out []Msg
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) {
+			out = append(out, Msg{Data: msg.Data(), Meta: msg.Metadata()})
+			if string(msg.Data()) == "bar" {
+				msg.Nack()
+			} else {
+				msg.Ack()
+			}
+			if len(out) == len(in) {
+				cancel()
+			}
+		})
+	}()
+
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	want := []Msg{
+		{Data: []byte("foo"), Meta: map[string]string{}},
+		{Data: []byte("bar"), Meta: map[string]string{"corge": "12", "id": "aaabbbccc"}},
+		{Data: []byte("baz"), Meta: map[string]string{}},
+	}
+	if diff := cmp.Diff(want, out); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+
+	pending, err := client.XPending(context.Background(), "test-stream", "test-group").Result()
+	orDie(t, err)
+	if got, want := pending.Count, int64(1); got != want {
+		t.Errorf("stream has %d pending entries, want %d", got, want)
+	}
+}
+
+func TestSubscriber_Reclaim(t *testing.T) {
+	orDie := func(t *testing.T, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	ctx := context.Background()
+	m, client := setup(t)
+
+	subscriber, err := redisstream.CreateSubscriber(
+		ctx, client, "test-stream", "test-group", "test-consumer",
+		redisstream.WithBlock(10*time.Millisecond),
+		redisstream.WithReclaim(time.Minute, 10*time.Millisecond),
+	)
+	orDie(t, err)
+
+	// A dead consumer receives the entry and never acks it.
+	now := time.Now()
+	m.SetTime(now)
+	orDie(t, client.XAdd(ctx, &redis.XAddArgs{Stream: "test-stream", Values: map[string]interface{}{"data": "foo"}}).Err())
+	orDie(t, client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "test-group",
+		Consumer: "dead-consumer",
+		Streams:  []string{"test-stream", ">"},
+	}).Err())
+	m.SetTime(now.Add(2 * time.Minute))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	msgCh := make(chan subee.Message, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- subscriber.Subscribe(ctx, func(msg subee.Message) {
+			msg.Ack()
+			msgCh <- msg
+		})
+	}()
+
+	select {
+	case msg := <-msgCh:
+		if got, want := string(msg.Data()), "foo"; got != want {
+			t.Errorf("Message.Data() is %q, want %q", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("pending entry was not reclaimed")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	pending, err := client.XPending(context.Background(), "test-stream", "test-group").Result()
+	orDie(t, err)
+	if got, want := pending.Count, int64(0); got != want {
+		t.Errorf("stream has %d pending entries, want %d", got, want)
+	}
+}
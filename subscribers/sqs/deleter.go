@@ -0,0 +1,123 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pkg/errors"
+)
+
+// maxDeleteBatchSize is the maximum number of entries of DeleteMessageBatch.
+const maxDeleteBatchSize = 10
+
+// deleter deletes acked messages in batch.
+type deleter struct {
+	client   API
+	queueURL string
+	interval time.Duration
+	timeout  time.Duration
+	onError  func(error)
+
+	mu      sync.Mutex
+	handles []*string
+	timer   *time.Timer
+	// wg tracks scheduled and running deletions.
+	wg sync.WaitGroup
+}
+
+func (d *deleter) add(receiptHandle *string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.handles = append(d.handles, receiptHandle)
+
+	switch {
+	case len(d.handles) >= maxDeleteBatchSize:
+		d.stopTimer()
+		handles := d.handles
+		d.handles = nil
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.report(d.delete(handles))
+		}()
+	case d.timer == nil:
+		d.wg.Add(1)
+		d.timer = time.AfterFunc(d.interval, func() {
+			defer d.wg.Done()
+			d.report(d.flush())
+		})
+	}
+}
+
+// stopTimer must be called with d.mu held.
+func (d *deleter) stopTimer() {
+	if d.timer != nil && d.timer.Stop() {
+		d.wg.Done()
+	}
+	d.timer = nil
+}
+
+func (d *deleter) flush() error {
+	d.mu.Lock()
+	handles := d.handles
+	d.handles = nil
+	d.stopTimer()
+	d.mu.Unlock()
+
+	if len(handles) == 0 {
+		return nil
+	}
+	return d.delete(handles)
+}
+
+// close deletes the buffered messages synchronously, and waits for the running deletions.
+func (d *deleter) close() error {
+	err := d.flush()
+	d.wg.Wait()
+	return err
+}
+
+func (d *deleter) report(err error) {
+	if err != nil {
+		d.onError(err)
+	}
+}
+
+func (d *deleter) delete(handles []*string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	entries := make([]types.DeleteMessageBatchRequestEntry, len(handles))
+	for i, h := range handles {
+		entries[i] = types.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: h,
+		}
+	}
+
+	out, err := d.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(d.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete %d sqs messages", len(handles))
+	}
+
+	if len(out.Failed) > 0 {
+		msgs := make([]string, len(out.Failed))
+		for i, f := range out.Failed {
+			msgs[i] = fmt.Sprintf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message))
+		}
+		return errors.Errorf("failed to delete %d of %d sqs messages: %s", len(out.Failed), len(handles), strings.Join(msgs, ", "))
+	}
+
+	return nil
+}
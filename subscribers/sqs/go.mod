@@ -0,0 +1,19 @@
+module github.com/wantedly/subee/subscribers/sqs
+
+go 1.21
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.32.7
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.37.3
+	github.com/google/go-cmp v0.5.9
+	github.com/pkg/errors v0.9.1
+	github.com/wantedly/subee v0.5.0
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.26 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.26 // indirect
+	github.com/aws/smithy-go v1.22.1 // indirect
+)
+
+replace github.com/wantedly/subee => ../..
@@ -0,0 +1,132 @@
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pkg/errors"
+)
+
+// Message is wrapps types.Message
+type Message struct {
+	types.Message
+	sub      *subscriberImpl
+	metadata map[string]string
+
+	settleOnce sync.Once
+	settledCh  chan struct{}
+}
+
+func newMessage(m types.Message, s *subscriberImpl) *Message {
+	metadata := make(map[string]string, len(m.MessageAttributes))
+	for k, v := range m.MessageAttributes {
+		switch {
+		case v.StringValue != nil:
+			metadata[k] = *v.StringValue
+		case v.BinaryValue != nil:
+			metadata[k] = string(v.BinaryValue)
+		}
+	}
+
+	return &Message{
+		Message:   m,
+		sub:       s,
+		metadata:  metadata,
+		settledCh: make(chan struct{}),
+	}
+}
+
+// Data returns the message body.
+func (m *Message) Data() []byte { return []byte(aws.ToString(m.Body)) }
+
+// Metadata returns message attributes.
+// Binary attributes are converted into strings.
+func (m *Message) Metadata() map[string]string { return m.metadata }
+
+// Ack deletes the message from the queue.
+// Acked messages are deleted in batch.
+func (m *Message) Ack() {
+	if m.settle() {
+		m.sub.deleter.add(m.ReceiptHandle)
+	}
+}
+
+// Nack makes the message visible again after the backoff set by WithNackBackoff, or immediately by default.
+func (m *Message) Nack() {
+	if !m.settle() {
+		return
+	}
+
+	m.sub.changeVisibility(m.ReceiptHandle, m.sub.nackBackoff(m.DeliveryAttempt()))
+}
+
+// MessageID returns the message ID.
+func (m *Message) MessageID() string { return aws.ToString(m.MessageId) }
+
+// PublishTime returns the time when the message was sent to the queue.
+func (m *Message) PublishTime() time.Time {
+	ms, err := strconv.ParseInt(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// DeliveryAttempt returns the approximate number of times the message has been received.
+func (m *Message) DeliveryAttempt() int {
+	n, _ := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	return n
+}
+
+// OrderingKey returns the message group ID of FIFO queues.
+func (m *Message) OrderingKey() string {
+	return m.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+}
+
+func (m *Message) settle() (ok bool) {
+	m.settleOnce.Do(func() {
+		close(m.settledCh)
+		ok = true
+	})
+	return
+}
+
+// extendVisibility extends the visibility of the message until it is settled or MaxExtension elapses.
+func (m *Message) extendVisibility() {
+	timeout := m.sub.VisibilityTimeout
+	deadline := time.Now().Add(m.sub.MaxExtension)
+
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.settledCh:
+			return
+		case now := <-ticker.C:
+			if now.After(deadline) {
+				return
+			}
+			m.sub.changeVisibility(m.ReceiptHandle, timeout)
+		}
+	}
+}
+
+func (s *subscriberImpl) changeVisibility(receiptHandle *string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.VisibilityTimeout)
+	defer cancel()
+
+	_, err := s.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.queueURL),
+		ReceiptHandle:     receiptHandle,
+		VisibilityTimeout: int32(timeout / time.Second),
+	})
+	if err != nil {
+		s.ErrorHandler(errors.Wrap(err, "failed to change the visibility of sqs message"))
+	}
+}
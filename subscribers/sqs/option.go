@@ -0,0 +1,148 @@
+package sqs
+
+import (
+	"log"
+	"time"
+
+	"github.com/wantedly/subee/internal/backoff"
+)
+
+const (
+	// DefaultMaxMessages is the default maximum number of messages received at once.
+	DefaultMaxMessages = 10
+	// DefaultWaitTime is the default duration of long polling.
+	DefaultWaitTime = 20 * time.Second
+	// DefaultVisibilityTimeout is the default visibility timeout of received messages.
+	DefaultVisibilityTimeout = 30 * time.Second
+	// DefaultMaxExtension is the default maximum duration to extend the visibility of a message.
+	DefaultMaxExtension = 12 * time.Hour
+	// DefaultDeleteInterval is the default interval to delete acked messages in batch.
+	DefaultDeleteInterval = 100 * time.Millisecond
+
+	// maxVisibilityTimeout is the maximum visibility timeout allowed by SQS.
+	maxVisibilityTimeout = 12 * time.Hour
+)
+
+// Config represents subscriber configuration.
+type Config struct {
+	Pollers           int
+	MaxMessages       int32
+	WaitTime          time.Duration
+	VisibilityTimeout time.Duration
+	MaxExtension      time.Duration
+	DeleteInterval    time.Duration
+
+	NackInitialBackoff time.Duration
+	NackMaxBackoff     time.Duration
+
+	ErrorHandler func(error)
+}
+
+func newDefaultConfig() *Config {
+	return &Config{
+		Pollers:           1,
+		MaxMessages:       DefaultMaxMessages,
+		WaitTime:          DefaultWaitTime,
+		VisibilityTimeout: DefaultVisibilityTimeout,
+		MaxExtension:      DefaultMaxExtension,
+		DeleteInterval:    DefaultDeleteInterval,
+		ErrorHandler:      func(err error) { log.Print(err) },
+	}
+}
+
+func (c *Config) apply(opts []Option) {
+	for _, f := range opts {
+		f(c)
+	}
+}
+
+// Option is subscriber Option
+type Option func(*Config)
+
+// WithPollers returns an Option that set the number of goroutines receiving messages in parallel.
+func WithPollers(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.Pollers = n
+		}
+	}
+}
+
+// WithMaxMessages returns an Option that set the maximum number of messages received at once, up to 10.
+func WithMaxMessages(n int32) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.MaxMessages = n
+		}
+	}
+}
+
+// WithWaitTime returns an Option that set the duration of long polling, up to 20 seconds.
+func WithWaitTime(d time.Duration) Option {
+	return func(c *Config) {
+		if d >= 0 {
+			c.WaitTime = d
+		}
+	}
+}
+
+// WithVisibilityTimeout returns an Option that set the visibility timeout of received messages.
+// The visibility of messages is extended by the timeout before it expires until they are acked or nacked.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		if d >= time.Second {
+			c.VisibilityTimeout = d
+		}
+	}
+}
+
+// WithMaxExtension returns an Option that set the maximum duration to extend the visibility of a message.
+func WithMaxExtension(d time.Duration) Option {
+	return func(c *Config) {
+		if d >= 0 {
+			c.MaxExtension = d
+		}
+	}
+}
+
+// WithDeleteInterval returns an Option that set the interval to delete acked messages in batch.
+// Acked messages are deleted at once when the interval elapses or 10 messages are acked.
+func WithDeleteInterval(d time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 {
+			c.DeleteInterval = d
+		}
+	}
+}
+
+// WithNackBackoff returns an Option that set the visibility timeout of nacked messages.
+// It starts with initial and doubles on each receive of the message, up to max.
+// If max is not positive, it is capped only by the maximum visibility timeout of SQS, 12 hours.
+// Nacked messages are visible immediately by default.
+func WithNackBackoff(initial, max time.Duration) Option {
+	return func(c *Config) {
+		c.NackInitialBackoff = initial
+		c.NackMaxBackoff = max
+	}
+}
+
+// WithErrorHandler returns an Option that set the function called with errors of deleting acked messages
+// and changing the visibility of messages, which are not returned by Subscribe.
+// The errors are logged with the standard logger by default.
+func WithErrorHandler(f func(error)) Option {
+	return func(c *Config) {
+		if f != nil {
+			c.ErrorHandler = f
+		}
+	}
+}
+
+// nackBackoff returns the visibility timeout of the message nacked after received count times.
+func (c *Config) nackBackoff(count int) time.Duration {
+	max := c.NackMaxBackoff
+	if max <= 0 || max > maxVisibilityTimeout {
+		max = maxVisibilityTimeout
+	}
+
+	return backoff.Exponential(c.NackInitialBackoff, max, count-1)
+}
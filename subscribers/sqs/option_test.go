@@ -0,0 +1,29 @@
+package sqs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_nackBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		max   time.Duration
+		count int
+		want  time.Duration
+	}{
+		{name: "doubled", max: time.Minute, count: 3, want: 40 * time.Second},
+		{name: "capped", max: time.Minute, count: 9, want: time.Minute},
+		{name: "not capped without max", count: 9, want: 2560 * time.Second},
+		{name: "capped by sqs without max", count: 100, want: 12 * time.Hour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newDefaultConfig()
+			cfg.apply([]Option{WithNackBackoff(10*time.Second, tc.max)})
+
+			if got := cfg.nackBackoff(tc.count); got != tc.want {
+				t.Errorf("nackBackoff(%d) is %v, want %v", tc.count, got, tc.want)
+			}
+		})
+	}
+}
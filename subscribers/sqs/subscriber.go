@@ -0,0 +1,120 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+)
+
+// API is the interface of the SQS operations used by the subscriber.
+// *sqs.Client implements it.
+type API interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+type subscriberImpl struct {
+	*Config
+	client   API
+	queueURL string
+	deleter  *deleter
+}
+
+// CreateSubscriber returns Subscriber implementation that receives messages from the queue.
+// Acked messages are deleted in batch, and the buffered ones are deleted on Close.
+func CreateSubscriber(client API, queueURL string, opts ...Option) (subee.Subscriber, error) {
+	cfg := newDefaultConfig()
+	cfg.apply(opts)
+
+	if len(queueURL) == 0 {
+		return nil, errors.New("missing sqs queue url")
+	}
+
+	return &subscriberImpl{
+		Config:   cfg,
+		client:   client,
+		queueURL: queueURL,
+		deleter: &deleter{
+			client:   client,
+			queueURL: queueURL,
+			interval: cfg.DeleteInterval,
+			timeout:  cfg.VisibilityTimeout,
+			onError:  cfg.ErrorHandler,
+		},
+	}, nil
+}
+
+func (s *subscriberImpl) Subscribe(ctx context.Context, f func(subee.Message)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		err     error
+	)
+
+	for i := 0; i < s.Pollers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if pollErr := s.poll(ctx, f); pollErr != nil {
+				errOnce.Do(func() {
+					err = pollErr
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.WithStack(err)
+}
+
+// Close deletes the acked messages buffered for batch deletion.
+// Engine calls it when Start returns, after all messages are settled.
+func (s *subscriberImpl) Close() error {
+	return errors.WithStack(s.deleter.close())
+}
+
+// poll receives messages with long polling until ctx is done.
+func (s *subscriberImpl) poll(ctx context.Context, f func(subee.Message)) error {
+	for ctx.Err() == nil {
+		out, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(s.queueURL),
+			MaxNumberOfMessages:   s.MaxMessages,
+			WaitTimeSeconds:       int32(s.WaitTime / time.Second),
+			VisibilityTimeout:     int32(s.VisibilityTimeout / time.Second),
+			MessageAttributeNames: []string{"All"},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameApproximateReceiveCount,
+				types.MessageSystemAttributeNameSentTimestamp,
+				types.MessageSystemAttributeNameMessageGroupId,
+			},
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "failed to receive sqs messages")
+		}
+
+		for _, m := range out.Messages {
+			msg := newMessage(m, s)
+			if s.MaxExtension > 0 {
+				go msg.extendVisibility()
+			}
+			f(msg)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,395 @@
+package sqs_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/wantedly/subee"
+	subee_sqs "github.com/wantedly/subee/subscribers/sqs"
+)
+
+func TestSubscriber(t *testing.T) {
+	srv := newFakeSQS(t)
+	srv.send("foo", nil)
+	srv.send("bar", map[string]string{"corge": "12", "id": "aaabbbccc"})
+	srv.send("baz", nil)
+
+	subscriber, err := subee_sqs.CreateSubscriber(srv.client(), srv.queueURL(), subee_sqs.WithWaitTime(0))
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	type Msg struct {
+		Data    []byte
+		Meta    map[string]string
+		ID      string
+		Attempt int
+	}
+
+	var out []Msg
+	ctx, cancel := context.WithCancel(context.Background())
+	err = subscriber.Subscribe(ctx, func(msg subee.Message) {
+		em := msg.(subee.ExtendedMessage)
+		if em.PublishTime().IsZero() {
+			t.Error("PublishTime() returned zero time")
+		}
+		out = append(out, Msg{Data: msg.Data(), Meta: msg.Metadata(), ID: em.MessageID(), Attempt: em.DeliveryAttempt()})
+		msg.Ack()
+		if len(out) == 3 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	want := []Msg{
+		{Data: []byte("foo"), Meta: map[string]string{}, ID: "0", Attempt: 1},
+		{Data: []byte("bar"), Meta: map[string]string{"corge": "12", "id": "aaabbbccc"}, ID: "1", Attempt: 1},
+		{Data: []byte("baz"), Meta: map[string]string{}, ID: "2", Attempt: 1},
+	}
+	if diff := cmp.Diff(want, out); diff != "" {
+		t.Errorf("Received message differs: (-want +got)\n%s", diff)
+	}
+
+	srv.waitDeleted(t, 3)
+	if got, want := srv.deleteBatches(), 1; got != want {
+		t.Errorf("DeleteMessageBatch was called %d times, want %d", got, want)
+	}
+}
+
+func TestSubscriber_Nack(t *testing.T) {
+	srv := newFakeSQS(t)
+	srv.send("foo", nil)
+
+	subscriber, err := subee_sqs.CreateSubscriber(
+		srv.client(), srv.queueURL(),
+		subee_sqs.WithWaitTime(0),
+		subee_sqs.WithNackBackoff(10*time.Second, 25*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	var received int
+	ctx, cancel := context.WithCancel(context.Background())
+	err = subscriber.Subscribe(ctx, func(msg subee.Message) {
+		received++
+		if received < 3 {
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	if diff := cmp.Diff([]int32{10, 20}, srv.visibilityTimeouts()); diff != "" {
+		t.Errorf("ChangeMessageVisibility timeouts differ: (-want +got)\n%s", diff)
+	}
+	srv.waitDeleted(t, 1)
+}
+
+func TestSubscriber_ExtendVisibility(t *testing.T) {
+	srv := newFakeSQS(t)
+	srv.send("foo", nil)
+
+	subscriber, err := subee_sqs.CreateSubscriber(
+		srv.client(), srv.queueURL(),
+		subee_sqs.WithWaitTime(0),
+		subee_sqs.WithVisibilityTimeout(time.Second),
+	)
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = subscriber.Subscribe(ctx, func(msg subee.Message) {
+		time.Sleep(1200 * time.Millisecond)
+		msg.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	if got, want := len(srv.visibilityTimeouts()), 2; got != want {
+		t.Errorf("visibility was extended %d times, want %d", got, want)
+	}
+	for _, timeout := range srv.visibilityTimeouts() {
+		if got, want := timeout, int32(1); got != want {
+			t.Errorf("visibility was extended by %d seconds, want %d", got, want)
+		}
+	}
+}
+
+func TestSubscriber_Close(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		invalidate  bool
+		wantDeleted int
+		wantErr     bool
+	}{
+		{name: "deletes buffered messages", wantDeleted: 2},
+		{name: "returns failed deletions", invalidate: true, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFakeSQS(t)
+			srv.send("foo", nil)
+			srv.send("bar", nil)
+
+			subscriber, err := subee_sqs.CreateSubscriber(
+				srv.client(), srv.queueURL(),
+				subee_sqs.WithWaitTime(0),
+				subee_sqs.WithDeleteInterval(time.Hour),
+			)
+			if err != nil {
+				t.Fatalf("CreateSubscriber returned an error: %v", err)
+			}
+
+			var received int
+			ctx, cancel := context.WithCancel(context.Background())
+			err = subscriber.Subscribe(ctx, func(msg subee.Message) {
+				msg.Ack()
+				if received++; received == 2 {
+					cancel()
+				}
+			})
+			if err != nil {
+				t.Errorf("Subscribe returned an error: %v", err)
+			}
+
+			if got, want := srv.deletedCount(), 0; got != want {
+				t.Errorf("%d messages were deleted before Close, want %d", got, want)
+			}
+			if tc.invalidate {
+				srv.invalidateHandles()
+			}
+
+			if err := subscriber.(io.Closer).Close(); (err != nil) != tc.wantErr {
+				t.Errorf("Close returned %v, want error %t", err, tc.wantErr)
+			}
+			if got, want := srv.deletedCount(), tc.wantDeleted; got != want {
+				t.Errorf("%d messages were deleted on Close, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestSubscriber_WithErrorHandler(t *testing.T) {
+	srv := newFakeSQS(t)
+	srv.send("foo", nil)
+
+	errCh := make(chan error, 1)
+	subscriber, err := subee_sqs.CreateSubscriber(
+		srv.client(), srv.queueURL(),
+		subee_sqs.WithWaitTime(0),
+		subee_sqs.WithErrorHandler(func(err error) { errCh <- err }),
+	)
+	if err != nil {
+		t.Fatalf("CreateSubscriber returned an error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = subscriber.Subscribe(ctx, func(msg subee.Message) {
+		srv.invalidateHandles()
+		msg.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Errorf("Subscribe returned an error: %v", err)
+	}
+
+	select {
+	case err := <-errCh:
+		if !strings.Contains(err.Error(), "ReceiptHandleIsInvalid") {
+			t.Errorf("ErrorHandler was called with %v, want the failed entry", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("ErrorHandler was not called with the failed deletion")
+	}
+}
+
+type fakeMessage struct {
+	body        string
+	attrs       map[string]string
+	handle      string
+	count       int
+	sentAt      time.Time
+	invisibleAt time.Time
+	visibleAt   time.Time
+}
+
+// fakeSQS is a HTTP server implementing a single queue of the SQS JSON protocol.
+type fakeSQS struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	msgs     []*fakeMessage
+	deleted  int
+	batches  int
+	timeouts []int32
+}
+
+func newFakeSQS(t *testing.T) *fakeSQS {
+	s := new(fakeSQS)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeSQS) client() *sqs.Client {
+	return sqs.New(sqs.Options{
+		Region:                           "us-east-1",
+		BaseEndpoint:                     aws.String(s.URL),
+		Credentials:                      aws.AnonymousCredentials{},
+		DisableMessageChecksumValidation: true,
+	})
+}
+
+func (s *fakeSQS) queueURL() string {
+	return s.URL + "/000000000000/test-queue"
+}
+
+func (s *fakeSQS) send(body string, attrs map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, &fakeMessage{body: body, attrs: attrs, sentAt: time.Now()})
+}
+
+// invalidateHandles invalidates the receipt handles of received messages as if they have been received again.
+func (s *fakeSQS) invalidateHandles() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.msgs {
+		m.handle = ""
+	}
+}
+
+func (s *fakeSQS) deletedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted
+}
+
+func (s *fakeSQS) deleteBatches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+func (s *fakeSQS) visibilityTimeouts() []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int32(nil), s.timeouts...)
+}
+
+func (s *fakeSQS) waitDeleted(t *testing.T, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		s.mu.Lock()
+		deleted := s.deleted
+		s.mu.Unlock()
+		if deleted == n {
+			return
+		}
+	}
+	t.Errorf("%d messages were not deleted", n)
+}
+
+func (s *fakeSQS) handle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MaxNumberOfMessages int
+		VisibilityTimeout   int32
+		ReceiptHandle       string
+		Entries             []struct{ Id, ReceiptHandle string }
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var resp interface{}
+	now := time.Now()
+
+	switch op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS."); op {
+	case "ReceiveMessage":
+		var msgs []map[string]interface{}
+		for i, m := range s.msgs {
+			if len(msgs) == req.MaxNumberOfMessages || now.Before(m.visibleAt) {
+				continue
+			}
+			m.count++
+			m.handle = strconv.Itoa(i) + "-" + strconv.Itoa(m.count)
+			m.visibleAt = now.Add(time.Duration(req.VisibilityTimeout) * time.Second)
+
+			attrs := make(map[string]interface{})
+			for k, v := range m.attrs {
+				attrs[k] = map[string]string{"DataType": "String", "StringValue": v}
+			}
+			msgs = append(msgs, map[string]interface{}{
+				"MessageId":     strconv.Itoa(i),
+				"ReceiptHandle": m.handle,
+				"Body":          m.body,
+				"Attributes": map[string]string{
+					"ApproximateReceiveCount": strconv.Itoa(m.count),
+					"SentTimestamp":           strconv.FormatInt(m.sentAt.UnixMilli(), 10),
+				},
+				"MessageAttributes": attrs,
+			})
+		}
+		resp = map[string]interface{}{"Messages": msgs}
+
+	case "DeleteMessageBatch":
+		s.batches++
+		ok := []map[string]string{}
+		failed := []map[string]interface{}{}
+	Entries:
+		for _, e := range req.Entries {
+			for i, m := range s.msgs {
+				if m.handle != "" && m.handle == e.ReceiptHandle {
+					s.msgs = append(s.msgs[:i], s.msgs[i+1:]...)
+					s.deleted++
+					ok = append(ok, map[string]string{"Id": e.Id})
+					continue Entries
+				}
+			}
+			failed = append(failed, map[string]interface{}{"Id": e.Id, "Code": "ReceiptHandleIsInvalid", "SenderFault": true})
+		}
+		resp = map[string]interface{}{"Successful": ok, "Failed": failed}
+
+	case "ChangeMessageVisibility":
+		s.timeouts = append(s.timeouts, req.VisibilityTimeout)
+		for _, m := range s.msgs {
+			if m.handle == req.ReceiptHandle {
+				// The timeout is only recorded, and the message becomes visible immediately to keep tests fast.
+				m.visibleAt = now
+			}
+		}
+		resp = struct{}{}
+
+	default:
+		http.Error(w, "unsupported operation: "+op, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(resp)
+}
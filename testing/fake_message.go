@@ -1,6 +1,12 @@
 package testing
 
-import "sync/atomic"
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/wantedly/subee"
+)
 
 // FakeMessage implements Message interface.
 type FakeMessage struct {
@@ -22,6 +28,11 @@ func NewFakeMessage(data []byte, acked, nacked bool) *FakeMessage {
 	return m
 }
 
+// NewFakeMessageWithMetadata creates a new FakeMessage object with the metadata.
+func NewFakeMessageWithMetadata(data []byte, metadata map[string]string) *FakeMessage {
+	return &FakeMessage{data: data, metadata: metadata}
+}
+
 // Data returns the message'm payload.
 func (m *FakeMessage) Data() []byte { return m.data }
 
@@ -39,3 +50,69 @@ func (m *FakeMessage) Acked() bool { return atomic.LoadInt32(&m.acked) == 1 }
 
 // Nacked returned true if the message has been nacked.
 func (m *FakeMessage) Nacked() bool { return atomic.LoadInt32(&m.nacked) == 1 }
+
+// FakeExtendedMessage implements ExtendedMessage interface.
+type FakeExtendedMessage struct {
+	*FakeMessage
+	id              string
+	publishTime     time.Time
+	deliveryAttempt int
+	orderingKey     string
+}
+
+// NewFakeExtendedMessage creates a new FakeExtendedMessage object.
+func NewFakeExtendedMessage(data []byte, id string, publishTime time.Time, deliveryAttempt int, orderingKey string) *FakeExtendedMessage {
+	return &FakeExtendedMessage{
+		FakeMessage:     &FakeMessage{data: data},
+		id:              id,
+		publishTime:     publishTime,
+		deliveryAttempt: deliveryAttempt,
+		orderingKey:     orderingKey,
+	}
+}
+
+// MessageID returns the message ID.
+func (m *FakeExtendedMessage) MessageID() string { return m.id }
+
+// PublishTime returns the time when the message was published.
+func (m *FakeExtendedMessage) PublishTime() time.Time { return m.publishTime }
+
+// DeliveryAttempt returns the number of deliveries of the message.
+func (m *FakeExtendedMessage) DeliveryAttempt() int { return m.deliveryAttempt }
+
+// OrderingKey returns the ordering key of the message.
+func (m *FakeExtendedMessage) OrderingKey() string { return m.orderingKey }
+
+// FakeResultMessage implements ResultAcknowledger interface.
+type FakeResultMessage struct {
+	*FakeMessage
+	ackErr  error
+	nackErr error
+}
+
+// NewFakeResultMessage creates a new FakeResultMessage object whose acknowledgements fail with ackErr and nackErr.
+func NewFakeResultMessage(data []byte, ackErr, nackErr error) *FakeResultMessage {
+	return &FakeResultMessage{
+		FakeMessage: &FakeMessage{data: data},
+		ackErr:      ackErr,
+		nackErr:     nackErr,
+	}
+}
+
+// AckWithResult acknowledges the message and returns the result.
+func (m *FakeResultMessage) AckWithResult() subee.AckResult {
+	m.Ack()
+	return fakeAckResult{err: m.ackErr}
+}
+
+// NackWithResult negatively acknowledges the message and returns the result.
+func (m *FakeResultMessage) NackWithResult() subee.AckResult {
+	m.Nack()
+	return fakeAckResult{err: m.nackErr}
+}
+
+type fakeAckResult struct {
+	err error
+}
+
+func (r fakeAckResult) Get(ctx context.Context) error { return r.err }
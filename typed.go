@@ -0,0 +1,185 @@
+package subee
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Codec is the interface to decode message payloads into T.
+type Codec[T any] interface {
+	Decode(data []byte) (T, error)
+}
+
+// CodecFunc type is an adapter to allow the use of ordinary functions as Codec.
+type CodecFunc[T any] func(data []byte) (T, error)
+
+// Decode call f(data)
+func (f CodecFunc[T]) Decode(data []byte) (T, error) {
+	return f(data)
+}
+
+// JSONCodec returns a Codec that decodes JSON payloads into T.
+func JSONCodec[T any]() Codec[T] {
+	return CodecFunc[T](func(data []byte) (T, error) {
+		var v T
+		err := json.Unmarshal(data, &v)
+		return v, errors.WithStack(err)
+	})
+}
+
+// TypedConsumer represents an interface that consume single decoded message.
+type TypedConsumer[T any] interface {
+	Consume(context.Context, T) error
+}
+
+// TypedConsumerFunc type is an adapter to allow the use of ordinary functions as TypedConsumer.
+type TypedConsumerFunc[T any] func(context.Context, T) error
+
+// Consume call f(ctx, obj)
+func (f TypedConsumerFunc[T]) Consume(ctx context.Context, obj T) error {
+	return errors.WithStack(f(ctx, obj))
+}
+
+// TypedBatchConsumer represents an interface that consume multiple decoded messages.
+type TypedBatchConsumer[T any] interface {
+	BatchConsume(context.Context, []T) error
+}
+
+// TypedBatchConsumerFunc type is an adapter to allow the use of ordinary functions as TypedBatchConsumer.
+type TypedBatchConsumerFunc[T any] func(context.Context, []T) error
+
+// BatchConsume call f(ctx, objs)
+func (f TypedBatchConsumerFunc[T]) BatchConsume(ctx context.Context, objs []T) error {
+	return errors.WithStack(f(ctx, objs))
+}
+
+// DecodeErrorHandler handles an error on decoding the message payload.
+// The returned error is regarded as the consumption error of the message.
+type DecodeErrorHandler func(ctx context.Context, msg Message, err error) error
+
+// NackOnDecodeError is a DecodeErrorHandler that nacks the message so that it is redelivered.
+func NackOnDecodeError(ctx context.Context, msg Message, err error) error {
+	return errors.WithStack(err)
+}
+
+// AckOnDecodeError is a DecodeErrorHandler that acks the message and reports it as dropped.
+func AckOnDecodeError(ctx context.Context, msg Message, err error) error {
+	return Permanent(err)
+}
+
+// TypedOption configures typed consumers.
+type TypedOption func(*typedConfig)
+
+type typedConfig struct {
+	decodeErrorHandler DecodeErrorHandler
+}
+
+func newTypedConfig(opts []TypedOption) *typedConfig {
+	c := &typedConfig{
+		decodeErrorHandler: NackOnDecodeError,
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// WithDecodeErrorHandler returns a TypedOption that sets the DecodeErrorHandler.
+// NackOnDecodeError is used by default.
+func WithDecodeErrorHandler(h DecodeErrorHandler) TypedOption {
+	return func(c *typedConfig) {
+		if h != nil {
+			c.decodeErrorHandler = h
+		}
+	}
+}
+
+// NewTyped creates a Consumer that decodes incoming messages into T with the codec.
+// The raw message can be retrieved by GetRawMessage in the consumer.
+func NewTyped[T any](codec Codec[T], consumer TypedConsumer[T], opts ...TypedOption) Consumer {
+	return &typedConsumer[T]{
+		codec:    codec,
+		consumer: consumer,
+		cfg:      newTypedConfig(opts),
+	}
+}
+
+type typedConsumer[T any] struct {
+	codec    Codec[T]
+	consumer TypedConsumer[T]
+	cfg      *typedConfig
+}
+
+func (c *typedConsumer[T]) Consume(ctx context.Context, msg Message) error {
+	obj, err := c.codec.Decode(msg.Data())
+	if err != nil {
+		return errors.WithStack(c.cfg.decodeErrorHandler(ctx, msg, err))
+	}
+	ctx = SetRawMessage(ctx, msg)
+	return errors.WithStack(c.consumer.Consume(ctx, obj))
+}
+
+// NewTypedBatch creates a BatchConsumer that decodes incoming messages into T with the codec.
+// Messages failed to be decoded are excluded from the batch and handled by the DecodeErrorHandler.
+// The raw messages corresponding to the decoded ones can be retrieved by GetRawMessages in the consumer.
+func NewTypedBatch[T any](codec Codec[T], consumer TypedBatchConsumer[T], opts ...TypedOption) BatchConsumer {
+	return &typedBatchConsumer[T]{
+		codec:    codec,
+		consumer: consumer,
+		cfg:      newTypedConfig(opts),
+	}
+}
+
+type typedBatchConsumer[T any] struct {
+	codec    Codec[T]
+	consumer TypedBatchConsumer[T]
+	cfg      *typedConfig
+}
+
+func (c *typedBatchConsumer[T]) BatchConsume(ctx context.Context, msgs []Message) error {
+	var (
+		objs    = make([]T, 0, len(msgs))
+		decoded = make([]Message, 0, len(msgs))
+		idxs    = make([]int, 0, len(msgs))
+		failed  = make(BatchError)
+	)
+
+	for i, msg := range msgs {
+		obj, err := c.codec.Decode(msg.Data())
+		if err != nil {
+			if err := c.cfg.decodeErrorHandler(ctx, msg, err); err != nil {
+				failed[i] = err
+			}
+			continue
+		}
+		objs = append(objs, obj)
+		decoded = append(decoded, msg)
+		idxs = append(idxs, i)
+	}
+
+	if len(objs) > 0 {
+		ctx = SetRawMessages(ctx, decoded)
+		err := c.consumer.BatchConsume(ctx, objs)
+		if err != nil {
+			var batchErr BatchError
+			partial := errors.As(err, &batchErr)
+			if !partial && len(failed) == 0 {
+				return errors.WithStack(err)
+			}
+			for i, idx := range idxs {
+				if !partial {
+					failed[idx] = err
+				} else if batchErr[i] != nil {
+					failed[idx] = batchErr[i]
+				}
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.WithStack(failed)
+}
@@ -0,0 +1,112 @@
+package subee_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/wantedly/subee"
+	subee_testing "github.com/wantedly/subee/testing"
+)
+
+type book struct {
+	Title string `json:"title"`
+}
+
+func TestNewTyped(t *testing.T) {
+	tests := []struct {
+		test    string
+		data    string
+		opts    []subee.TypedOption
+		want    *book
+		errKind func(error) bool
+	}{
+		{
+			test: "decoded",
+			data: `{"title":"foo"}`,
+			want: &book{Title: "foo"},
+		},
+		{
+			test:    "nacked when failed to decode",
+			data:    `{"title":`,
+			errKind: subee.IsRetryable,
+		},
+		{
+			test:    "acked when failed to decode with AckOnDecodeError",
+			data:    `{"title":`,
+			opts:    []subee.TypedOption{subee.WithDecodeErrorHandler(subee.AckOnDecodeError)},
+			errKind: subee.IsPermanent,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.test, func(t *testing.T) {
+			var got *book
+			msg := subee_testing.NewFakeMessage([]byte(test.data), false, false)
+
+			err := subee.NewTyped(
+				subee.JSONCodec[*book](),
+				subee.TypedConsumerFunc[*book](func(ctx context.Context, b *book) error {
+					if got, want := subee.GetRawMessage(ctx), subee.Message(msg); got != want {
+						t.Errorf("GetRawMessage() returned %v, want %v", got, want)
+					}
+					got = b
+					return nil
+				}),
+				test.opts...,
+			).Consume(context.Background(), msg)
+
+			if test.errKind == nil {
+				if err != nil {
+					t.Errorf("Consume() returned %v, want nil", err)
+				}
+			} else if !test.errKind(err) {
+				t.Errorf("Consume() returned unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("consumed %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewTypedBatch(t *testing.T) {
+	msgs := []subee.Message{
+		subee_testing.NewFakeMessage([]byte(`{"title":"foo"}`), false, false),
+		subee_testing.NewFakeMessage([]byte(`{"title":`), false, false),
+		subee_testing.NewFakeMessage([]byte(`{"title":"bar"}`), false, false),
+		subee_testing.NewFakeMessage([]byte(`{"title":"baz"}`), false, false),
+	}
+
+	var got []*book
+	err := subee.NewTypedBatch(
+		subee.JSONCodec[*book](),
+		subee.TypedBatchConsumerFunc[*book](func(ctx context.Context, books []*book) error {
+			if got, want := len(subee.GetRawMessages(ctx)), len(books); got != want {
+				t.Errorf("GetRawMessages() returned %d messages, want %d", got, want)
+			}
+			got = books
+			return subee.BatchError{1: errors.New("error")}
+		}),
+	).BatchConsume(context.Background(), msgs)
+
+	if want := []*book{{Title: "foo"}, {Title: "bar"}, {Title: "baz"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("consumed %v, want %v", got, want)
+	}
+
+	var batchErr subee.BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchConsume() returned %v, want subee.BatchError", err)
+	}
+	var idxs []int
+	for i := 0; i < len(msgs); i++ {
+		if batchErr[i] != nil {
+			idxs = append(idxs, i)
+		}
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(idxs, want) {
+		t.Errorf("BatchConsume() failed messages %v, want %v", idxs, want)
+	}
+}